@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/natedelduca/go-rag-pack/internal/output"
+)
+
+// logger receives warnings, errors and progress notes on stderr. Commands
+// replace it once their --log-level and --log-json flags are parsed; results
+// such as "wrote N chunks" stay on stdout.
+var logger = newLogger(os.Stderr, slog.LevelInfo, false)
+
+// addLogFlags registers --log-level and --log-json on fs. The returned
+// function installs the configured logger and must run after fs.Parse.
+func addLogFlags(fs *flag.FlagSet) func() error {
+	level := fs.String("log-level", "info", "minimum log level: debug, info, warn or error")
+	asJSON := fs.Bool("log-json", false, "write logs as JSON lines for CI")
+	return func() error {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(*level)); err != nil {
+			return fmt.Errorf("unknown log level %q (want debug, info, warn or error)", *level)
+		}
+		logger = newLogger(os.Stderr, lvl, *asJSON)
+		return nil
+	}
+}
+
+// newLogger returns a JSON logger, or one writing "warning: message key=value"
+// lines, with the level prefix colored when w is a terminal and NO_COLOR is
+// unset.
+func newLogger(w io.Writer, level slog.Level, asJSON bool) *slog.Logger {
+	if asJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+	}
+	return slog.New(&textHandler{w: w, level: level, color: isTerminal(w), mu: new(sync.Mutex)})
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// textHandler is the human-readable slog.Handler. Info lines carry no
+// prefix, matching the tool's plain progress notes. Groups are flattened.
+type textHandler struct {
+	w     io.Writer
+	level slog.Level
+	color bool
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var prefix, color string
+	switch {
+	case r.Level >= slog.LevelError:
+		prefix, color = "error: ", "\x1b[31m"
+	case r.Level >= slog.LevelWarn:
+		prefix, color = "warning: ", "\x1b[33m"
+	case r.Level < slog.LevelInfo:
+		prefix, color = "debug: ", "\x1b[90m"
+	}
+	var buf strings.Builder
+	if h.color && prefix != "" {
+		buf.WriteString(color + prefix + "\x1b[0m")
+	} else {
+		buf.WriteString(prefix)
+	}
+	buf.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		value := a.Value.Resolve().String()
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, " %s=%s", a.Key, value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	buf.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, buf.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)
+	return &clone
+}
+
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// warningRecorder passes every record on to next and keeps the warnings and
+// errors for the build report.
+type warningRecorder struct {
+	next     slog.Handler
+	attrs    []slog.Attr
+	mu       *sync.Mutex
+	warnings *[]output.ReportWarning
+}
+
+// recordWarnings wraps logger so warnings and errors logged through the
+// returned logger are also appended to the returned slice.
+func recordWarnings(logger *slog.Logger) (*slog.Logger, *[]output.ReportWarning) {
+	warnings := new([]output.ReportWarning)
+	return slog.New(&warningRecorder{next: logger.Handler(), mu: new(sync.Mutex), warnings: warnings}), warnings
+}
+
+func (h *warningRecorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.next.Enabled(ctx, level)
+}
+
+func (h *warningRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		w := output.ReportWarning{Level: strings.ToLower(r.Level.String()), Message: r.Message}
+		addField := func(a slog.Attr) bool {
+			if w.Fields == nil {
+				w.Fields = make(map[string]string)
+			}
+			w.Fields[a.Key] = a.Value.Resolve().String()
+			return true
+		}
+		for _, a := range h.attrs {
+			addField(a)
+		}
+		r.Attrs(addField)
+		h.mu.Lock()
+		*h.warnings = append(*h.warnings, w)
+		h.mu.Unlock()
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *warningRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *warningRecorder) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/natedelduca/go-rag-pack/internal/chunk"
 	"github.com/natedelduca/go-rag-pack/internal/config"
 	"github.com/natedelduca/go-rag-pack/internal/discover"
+	"github.com/natedelduca/go-rag-pack/internal/embed"
 	"github.com/natedelduca/go-rag-pack/internal/output"
 	"github.com/natedelduca/go-rag-pack/internal/ui"
+	"github.com/natedelduca/go-rag-pack/ragpack"
 )
 
 func main() {
@@ -35,6 +41,12 @@ func main() {
 		err = runSelect(args)
 	case "build":
 		err = runBuild(args)
+	case "clean":
+		err = runClean(args)
+	case "serve":
+		err = runServe(args)
+	case "schema":
+		err = runSchema(args)
 	case "help", "-h", "--help":
 		usage()
 	default:
@@ -44,7 +56,7 @@ func main() {
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
 }
@@ -54,14 +66,24 @@ func usage() {
 
 Usage:
   go-rag-pack init [--config path]
-  go-rag-pack select [--config path]
-  go-rag-pack build [--config path] [--output path] [--auto]
+  go-rag-pack select [--config path] [--strict-config=false] [--refresh] [--include-indirect=false] [--from selection.json | --list-modules [--json]]
+  go-rag-pack build [--config path] [--strict-config=false] [--output path] [--auto] [--pkg importpath]... [--exclude-module path]... [--refresh] [--stream] [--watch] [--since ref | --only-changed-modules] [--retries n] [--force] [--download] [--skip-errors] [--exported] [--require-doc]
+                    [--include-tests] [--test-packages internal|external] [--manual-scan-depth n]
+                    [--symbol-filter regexp] [--symbol-filter-ignore-case] [--with-types] [--only-symbols names] [--kind kind]...
+                    [--stdlib-groups list] [--sort path|source] [--max-chunks n [--prioritize]] [--format jsonl|llamaindex|csv] [--gzip] [--split-by-kind] [--symbol-index path] [--index path] [--report-json path] [--relations path]
+                    [--sqlite path] [--coverage-report path] [--topic-map path] [--versions path] [--embed openai|local]
+                    [--coverprofile path | --test-coverage]
+  go-rag-pack clean [--config path] [--strict-config=false] [--force]
+  go-rag-pack serve [--config path] [--strict-config=false] [--addr :8080] [--refresh]
+  go-rag-pack schema
+
+select, build, clean and serve also accept [--log-level debug|info|warn|error] [--log-json].
 `)
 }
 
 func runInit(args []string) error {
 	fs := flag.NewFlagSet("init", flag.ExitOnError)
-	configPath := fs.String("config", config.DefaultFile, "config file path")
+	configPath := fs.String("config", "", "config file path (default .go-rag-pack.json, or an existing .yaml/.yml)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -74,199 +96,389 @@ func runInit(args []string) error {
 	cfg := config.Default(root)
 	cfg.LastProjectRoot = root
 
-	return config.Save(resolvePath(root, *configPath), cfg)
+	return config.Save(configFile(root, *configPath), cfg)
+}
+
+// runSchema prints the JSON Schema of a build's JSONL output lines.
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output.ChunkSchema())
 }
 
 func runSelect(args []string) error {
 	fs := flag.NewFlagSet("select", flag.ExitOnError)
-	configPath := fs.String("config", config.DefaultFile, "config file path")
+	setupLog := addLogFlags(fs)
+	configPath := fs.String("config", "", "config file path (default .go-rag-pack.json, or an existing .yaml/.yml)")
+	strictConfig := fs.Bool("strict-config", true, "reject unknown config keys and values; =false ignores them")
+	refresh := fs.Bool("refresh", false, "ignore cached go list output")
+	includeIndirect := fs.Bool("include-indirect", true, "list modules only reached through other dependencies")
+	from := fs.String("from", "", "read the selection from this JSON file instead of prompting")
+	listModules := fs.Bool("list-modules", false, "print the discovered third-party modules and exit")
+	jsonOutput := fs.Bool("json", false, "with --list-modules, print JSON")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := setupLog(); err != nil {
+		return err
+	}
 
-	root, err := os.Getwd()
+	root, err := projectRoot(*configPath)
 	if err != nil {
 		return err
 	}
 
-	cfg, err := loadOrDefault(root, *configPath)
+	effective, err := ragpack.LoadConfig(root, *configPath, *strictConfig)
 	if err != nil {
 		return err
 	}
-
-	project, err := discover.Discover(root)
+	// The selection is written back to the repo file alone, so global and
+	// local settings and environment overrides stay out of it.
+	path := configFile(root, *configPath)
+	cfg, err := config.LoadRepo(root, path, *strictConfig)
 	if err != nil {
 		return err
 	}
 
-	selection, err := ui.RunSelection(project, cfg)
+	project, err := discoverProject(root, *refresh, effective.GoListRetries)
 	if err != nil {
 		return err
 	}
+	if *listModules {
+		return printModules(os.Stdout, project.ThirdParty, *includeIndirect, *jsonOutput)
+	}
+
+	var selection ui.Selection
+	if *from != "" {
+		if selection, err = ui.LoadSelection(resolvePath(root, *from), project); err != nil {
+			return err
+		}
+	} else {
+		// Hidden indirect modules keep whatever selection they already had.
+		var hidden []string
+		if !*includeIndirect {
+			shown := project.ThirdParty[:0:0]
+			for _, mu := range project.ThirdParty {
+				if mu.Relation == discover.RelationIndirect {
+					if slices.Contains(cfg.SelectedModules, mu.Module.Path) {
+						hidden = append(hidden, mu.Module.Path)
+					}
+					continue
+				}
+				shown = append(shown, mu)
+			}
+			project.ThirdParty = shown
+		}
+
+		if selection, err = ui.RunSelection(project, cfg); err != nil {
+			return err
+		}
+		selection.SelectedModules = append(selection.SelectedModules, hidden...)
+	}
 
 	cfg.IncludeProject = selection.IncludeProject
 	cfg.IncludeStdlib = selection.IncludeStdlib
+	if selection.IncludeStdlib {
+		cfg.ExcludeStdlib = selection.ExcludeStdlib
+	}
 	if selection.IncludeModules {
 		cfg.SelectedModules = selection.SelectedModules
 		cfg.ManualModules = selection.ManualModules
+		cfg.SelectedPackages = selection.SelectedPackages
 	} else {
 		cfg.SelectedModules = nil
 		cfg.ManualModules = nil
+		cfg.SelectedPackages = nil
 	}
 	cfg.LastProjectRoot = root
 
-	return config.Save(resolvePath(root, *configPath), cfg)
+	return config.Save(path, cfg)
+}
+
+// listedModule is the view of a discovered module printed by
+// select --list-modules --json.
+type listedModule struct {
+	Path     string `json:"path"`
+	Version  string `json:"version,omitempty"`
+	Relation string `json:"relation"`
+	Packages int    `json:"packages"`
+}
+
+// printModules writes modules to w, one per line or as a JSON array,
+// leaving out indirect ones unless includeIndirect is set.
+func printModules(w io.Writer, modules []discover.ModuleUsage, includeIndirect, asJSON bool) error {
+	listed := make([]listedModule, 0, len(modules))
+	for _, mu := range modules {
+		if !includeIndirect && mu.Relation == discover.RelationIndirect {
+			continue
+		}
+		listed = append(listed, listedModule{
+			Path:     mu.Module.Path,
+			Version:  mu.Module.Version,
+			Relation: mu.Relation,
+			Packages: len(mu.Packages),
+		})
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listed)
+	}
+	for _, m := range listed {
+		name := m.Path
+		if m.Version != "" {
+			name += "@" + m.Version
+		}
+		fmt.Fprintf(w, "%s (%s, %d package(s))\n", name, m.Relation, m.Packages)
+	}
+	return nil
 }
 
 func runBuild(args []string) error {
 	fs := flag.NewFlagSet("build", flag.ExitOnError)
-	configPath := fs.String("config", config.DefaultFile, "config file path")
+	setupLog := addLogFlags(fs)
+	configPath := fs.String("config", "", "config file path (default .go-rag-pack.json, or an existing .yaml/.yml)")
+	strictConfig := fs.Bool("strict-config", true, "reject unknown config keys and values; =false ignores them")
 	outputPath := fs.String("output", "", "output file path (overrides config)")
 	auto := fs.Bool("auto", false, "select everything automatically")
+	refresh := fs.Bool("refresh", false, "ignore cached go list output")
+	symbolIndex := fs.String("symbol-index", "", "also write a flat JSON symbol index to this path")
+	lookupIndex := fs.String("index", "", "also write a JSON object mapping each exported symbol name to its chunks to this path")
+	reportJSON := fs.String("report-json", "", "also write a JSON summary of the build (counts, bytes, outputs, duration, warnings) to this path")
+	skipErrors := fs.Bool("skip-errors", false, "log and skip files that fail to parse instead of aborting")
+	sortOrder := fs.String("sort", "", "chunk order: path (default) or source")
+	streamFlag := fs.Bool("stream", false, "chunk project packages while dependency discovery is still running")
+	since := fs.String("since", "", "re-chunk only project packages changed since this git ref, merging into the existing output")
+	onlyChangedModules := fs.Bool("only-changed-modules", false, "with --versions, re-chunk only the project and the modules whose version changed since the recorded versions, merging into the existing output")
+	coverProfile := fs.String("coverprofile", "", "annotate function chunks with test coverage from this go test -coverprofile file")
+	testCoverage := fs.Bool("test-coverage", false, "run go test -coverprofile on the project and annotate function chunks with the result")
+	maxChunks := fs.Int("max-chunks", 0, "keep at most this many chunks, in output order (0 = unlimited)")
+	prioritize := fs.Bool("prioritize", false, "with --max-chunks, keep exported and documented declarations first")
+	retries := fs.Int("retries", -1, "retry go list module and dependency listings this many times on network or proxy errors (-1 = goListRetries from the config)")
+	force := fs.Bool("force", false, "build even when more packages are selected than maxPackages allows")
+	download := fs.Bool("download", false, "run go mod download for selected modules missing from the module cache")
+	watch := fs.Bool("watch", false, "rebuild whenever a .go file in the project or a local replace directory changes")
+	splitByKind := fs.Bool("split-by-kind", false, "write project, stdlib and third-party chunks to separate files next to the output path")
+	gzipOutput := fs.Bool("gzip", false, "gzip-compress the output, adding .gz to the output path")
+	format := fs.String("format", "", "output format: jsonl (default), llamaindex or csv")
+	embedProvider := fs.String("embed", "", "embed each chunk with this provider (openai or local) and write its vector")
+	sqlitePath := fs.String("sqlite", "", "also write chunks to a SQLite database with full-text search at this path")
+	coverageReport := fs.String("coverage-report", "", "also write a JSON report of emitted vs excluded declarations to this path")
+	versions := fs.String("versions", "", "also write the indexed module versions to this path and warn when they changed since the last build")
+	relations := fs.String("relations", "", "also write chunk relationship edges as JSON to this path")
+	exported := fs.Bool("exported", false, "only chunk exported declarations")
+	manualScanDepth := fs.Int("manual-scan-depth", 0, "scan manually added modules at most this many directories deep (0 = unlimited)")
+	includeTests := fs.Bool("include-tests", false, "also chunk _test.go files")
+	testPackages := fs.String("test-packages", "", "with --include-tests, keep only internal or external test packages")
+	requireDoc := fs.Bool("require-doc", false, "skip functions, types and values without a doc comment")
+	symbolFilter := fs.String("symbol-filter", "", "only chunk declarations whose name matches this regexp")
+	stdlibGroups := fs.String("stdlib-groups", "", "comma-separated curated stdlib groups to include (net, crypto, encoding, concurrency)")
+	var pkgPaths stringList
+	var kindFlags stringList
+	fs.Var(&kindFlags, "kind", "only build chunks of this kind: function, type, const, var, field, file-doc, package-doc, directive, usage, module-info, signature, type-bundle, native-code or command (repeatable)")
+	fs.Var(&pkgPaths, "pkg", "chunk only this import path, ignoring project/stdlib/module selection (repeatable)")
+	var excludeModules stringList
+	fs.Var(&excludeModules, "exclude-module", "drop this module, or every module under a path ending in /, from the selected sources, after --auto and config selection (repeatable)")
+	topicMap := fs.String("topic-map", "", "JSON file of symbol-name glob to topic rules")
+	onlySymbols := fs.String("only-symbols", "", "comma-separated exact symbol names to chunk, e.g. Foo,Bar.Method")
+	symbolFilterIgnoreCase := fs.Bool("symbol-filter-ignore-case", false, "match --symbol-filter case-insensitively")
+	withTypes := fs.Bool("with-types", false, "type-check the project and record on type chunks the interfaces each type implements")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-
-	root, err := os.Getwd()
-	if err != nil {
+	if err := setupLog(); err != nil {
 		return err
 	}
+	start := time.Now()
 
-	project, err := discover.Discover(root)
+	root, err := projectRoot(*configPath)
 	if err != nil {
 		return err
 	}
 
-	cfg, err := loadOrDefault(root, *configPath)
+	if *watch {
+		rest := dropFlag(args, "watch")
+		return watchBuild(root, func() error { return runBuild(rest) })
+	}
+
+	// Record this build's warnings for --report-json. The wrapper is undone
+	// on return so rebuilds under --watch each start from a clean logger.
+	var warnings *[]output.ReportWarning
+	if *reportJSON != "" {
+		base := logger
+		logger, warnings = recordWarnings(logger)
+		defer func() { logger = base }()
+	}
+
+	cfg, err := ragpack.LoadConfig(root, *configPath, *strictConfig)
 	if err != nil {
 		return err
 	}
 
-	if *auto {
-		cfg.IncludeProject = true
-		cfg.IncludeStdlib = len(project.StdlibPackages) > 0
-		cfg.SelectedModules = nil
-		for _, mod := range project.ThirdParty {
-			cfg.SelectedModules = append(cfg.SelectedModules, mod.Module.Path)
+	if len(pkgPaths) > 0 {
+		if *auto {
+			return errors.New("--pkg and --auto cannot be combined")
 		}
+		cfg.IncludeProject = false
+		cfg.IncludeStdlib = false
+		cfg.StdlibGroups = nil
+		cfg.SelectedModules = nil
 		cfg.ManualModules = nil
 	}
 
-	selectedModules := make(map[string]struct{})
-	for _, mod := range cfg.SelectedModules {
-		selectedModules[mod] = struct{}{}
+	if *retries >= 0 {
+		cfg.GoListRetries = *retries
 	}
-	for _, mod := range cfg.ManualModules {
-		selectedModules[mod] = struct{}{}
+	if *exported {
+		cfg.ExportedOnly = true
 	}
-
-	var sources []chunk.PackageSource
-	if cfg.IncludeProject {
-		for _, pkg := range project.InternalPackages {
-			sources = append(sources, chunk.PackageSource{
-				ModulePath:    project.MainModule.Path,
-				ModuleVersion: project.MainModule.Version,
-				ModuleDir:     project.Root,
-				ImportPath:    pkg.ImportPath,
-				Dir:           pkg.Dir,
-				Kind:          chunk.SourceProject,
-			})
+	if *requireDoc {
+		cfg.RequireDoc = true
+	}
+	if *includeTests {
+		cfg.IncludeTests = true
+	}
+	if *manualScanDepth > 0 {
+		cfg.ManualScanDepth = *manualScanDepth
+	}
+	if *testPackages != "" {
+		cfg.TestPackages = *testPackages
+	}
+	if *symbolFilter != "" {
+		cfg.SymbolFilter = *symbolFilter
+	}
+	if *withTypes {
+		cfg.WithTypes = true
+	}
+	if *symbolFilterIgnoreCase {
+		cfg.SymbolFilterIgnoreCase = true
+	}
+	if *onlySymbols != "" {
+		cfg.OnlySymbols = splitList(*onlySymbols)
+	}
+	if len(kindFlags) > 0 {
+		cfg.Kinds = kindFlags
+	}
+	if *coverProfile != "" {
+		cfg.CoverProfile = *coverProfile
+	}
+	if *skipErrors {
+		cfg.SkipErrors = true
+	}
+	if *sortOrder != "" {
+		cfg.Sort = *sortOrder
+	}
+	if *format != "" {
+		cfg.Format = *format
+	}
+	var idTemplate *chunk.IDTemplate
+	if cfg.IDTemplate != "" {
+		if idTemplate, err = chunk.ParseIDTemplate(cfg.IDTemplate); err != nil {
+			return err
 		}
 	}
-
-	if cfg.IncludeStdlib {
-		goRoot := runtime.GOROOT()
-		stdRoot := filepath.Join(goRoot, "src")
-		for _, pkg := range project.StdlibPackages {
-			if pkg.Dir == "" {
-				continue
-			}
-			sources = append(sources, chunk.PackageSource{
-				ModulePath:    "std",
-				ModuleVersion: "",
-				ModuleDir:     stdRoot,
-				ImportPath:    pkg.ImportPath,
-				Dir:           pkg.Dir,
-				Kind:          chunk.SourceStdlib,
-			})
+	writeOutput, outputExt, err := ragpack.WriterFor(cfg.Format)
+	if err != nil {
+		return err
+	}
+	if *embedProvider != "" {
+		cfg.Embed = *embedProvider
+	}
+	var embedder embed.Embedder
+	if cfg.Embed != "" {
+		if embedder, err = embed.New(cfg.Embed, embed.Settings{
+			Model:  cfg.EmbedModel,
+			URL:    cfg.EmbedURL,
+			APIKey: os.Getenv("OPENAI_API_KEY"),
+		}); err != nil {
+			return err
 		}
 	}
-
-	if len(selectedModules) > 0 {
-		modUsage := make(map[string]discover.ModuleUsage)
-		for _, mu := range project.ThirdParty {
-			modUsage[mu.Module.Path] = mu
+	if *stdlibGroups != "" {
+		cfg.StdlibGroups = splitList(*stdlibGroups)
+	}
+	if *topicMap != "" {
+		cfg.TopicMap = *topicMap
+	}
+	opts, err := ragpack.ChunkOptions(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.TopicMap != "" {
+		if opts.Topics, err = chunk.LoadTopicRules(resolvePath(root, cfg.TopicMap)); err != nil {
+			return err
 		}
-		allModules := make(map[string]discover.Module)
-		for _, mod := range project.AllModules {
-			allModules[mod.Path] = mod
+	}
+	if *coverageReport != "" || cfg.RequireDoc || overridesRequireDoc(cfg) {
+		opts.Coverage = chunk.NewCoverage()
+	}
+	if cfg.RepoURLTemplate != "" {
+		if commit, err := gitHead(root); err != nil {
+			logger.Warn("repo URL template: no commit", "err", err)
+		} else {
+			opts.Commit = commit
 		}
+	}
+	if cfg.WithTypes {
+		opts.Implements = ragpack.Implementations(root, logger)
+	}
+	opts.OnLargeFile = func(path string, size int64) {
+		logger.Warn("skipping file larger than maxFileBytes", "path", path, "bytes", size, "maxFileBytes", cfg.MaxFileBytes)
+	}
+	var skippedFiles int
+	if cfg.SkipErrors {
+		opts.OnFileError = func(path string, err error) {
+			skippedFiles++
+			logger.Warn("skipping file", "path", path, "err", err)
+		}
+	}
 
-		for path := range selectedModules {
-			if mu, ok := modUsage[path]; ok {
-				for _, pkg := range mu.Packages {
-					dir := pkg.Dir
-					if dir == "" && pkg.Module != nil {
-						dir = pkg.Module.Dir
-					}
-					if dir == "" {
-						continue
-					}
-					moduleDir := mu.Module.Dir
-					if moduleDir == "" && pkg.Module != nil {
-						moduleDir = pkg.Module.Dir
-					}
-					if moduleDir == "" {
-						moduleDir = dir
-					}
-					sources = append(sources, chunk.PackageSource{
-						ModulePath:    mu.Module.Path,
-						ModuleVersion: mu.Module.Version,
-						ModuleDir:     moduleDir,
-						ImportPath:    pkg.ImportPath,
-						Dir:           dir,
-						Kind:          chunk.SourceThirdParty,
-					})
-				}
-				continue
-			}
+	// Streaming starts chunking project packages while dependency discovery
+	// below is still running.
+	var stream *streamBuild
+	if (*streamFlag || cfg.Stream) && (cfg.IncludeProject || *auto) {
+		stream = startStream(root, opts)
+	}
 
-			// Manual module handling: discover packages by scanning the module directory.
-			module, ok := allModules[path]
-			if !ok {
-				fmt.Fprintf(os.Stderr, "warning: module %s not found; skipping\n", path)
-				continue
-			}
-			if module.Dir == "" {
-				fmt.Fprintf(os.Stderr, "warning: module %s has no source directory; skipping\n", path)
-				continue
-			}
-			pkgs, err := scanModulePackages(module)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warning: module %s: %v\n", path, err)
-				continue
-			}
-			for _, pkg := range pkgs {
-				sources = append(sources, chunk.PackageSource{
-					ModulePath:    module.Path,
-					ModuleVersion: module.Version,
-					ModuleDir:     module.Dir,
-					ImportPath:    pkg.ImportPath,
-					Dir:           pkg.Dir,
-					Kind:          chunk.SourceThirdParty,
-				})
-			}
-		}
+	project, err := discoverProject(root, *refresh, cfg.GoListRetries)
+	if err != nil {
+		return err
 	}
 
-	if len(sources) == 0 {
-		return errors.New("no sources selected; run go-rag-pack select or use --auto")
+	if err := ragpack.CheckModuleOverrides(cfg, project); err != nil {
+		return err
+	}
+	if *auto {
+		cfg = ragpack.SelectAll(cfg, project)
 	}
 
-	chunks, err := chunk.Build(dedupeSources(sources))
+	sources, err := ragpack.Sources(root, cfg, project, ragpack.SourceOptions{
+		Packages:    pkgPaths,
+		SkipProject: stream != nil,
+		Download:    *download,
+		Logger:      logger,
+	})
 	if err != nil {
 		return err
 	}
+	if stream != nil {
+		// Streamed project chunks are already on their way.
+		main := []chunk.PackageSource{{ModulePath: project.MainModule.Path}}
+		if _, kept, _ := ragpack.ExcludeModules(cfg, main, excludeModules); len(kept) == 0 {
+			return errors.New("--exclude-module cannot drop the main module with --stream")
+		}
+	}
+	cfg, sources, unmatchedExcludes := ragpack.ExcludeModules(cfg, sources, excludeModules)
+	for _, pattern := range unmatchedExcludes {
+		logger.Warn("excluded module is not among the selected sources", "module", pattern)
+	}
+	if len(sources) == 0 && stream == nil {
+		return errors.New("no sources selected; run go-rag-pack select or use --auto")
+	}
 
 	outPath := cfg.OutputPath
 	if *outputPath != "" {
@@ -275,167 +487,797 @@ func runBuild(args []string) error {
 	if outPath == "" {
 		outPath = filepath.Join("rag", "go_docs.jsonl")
 	}
+	perPackage := ragpack.IsDirOutput(outPath)
+	if perPackage && *splitByKind {
+		return errors.New("--split-by-kind needs an output file path, not a directory")
+	}
+	if *gzipOutput {
+		if perPackage {
+			outputExt += ".gz"
+		} else if !strings.HasSuffix(outPath, ".gz") {
+			outPath += ".gz"
+		}
+	}
 	absOut := resolvePath(root, outPath)
-	if err := output.WriteJSONL(absOut, chunks); err != nil {
+
+	// With --since, unchanged packages keep their chunks from the previous
+	// output and only the project packages touched since the ref are rebuilt.
+	var kept []chunk.Chunk
+	incremental := false
+	if *since != "" {
+		if stream != nil {
+			return errors.New("--since and --stream cannot be combined")
+		}
+		if (cfg.Format != "" && cfg.Format != ragpack.FormatJSONL) || perPackage || *splitByKind {
+			return fmt.Errorf("--since needs a single %s output file to merge into", ragpack.FormatJSONL)
+		}
+		if *maxChunks > 0 {
+			return errors.New("--since cannot be combined with --max-chunks")
+		}
+		if cfg.PathStyle == chunk.PathImportPath {
+			// Changed files are matched against chunk paths.
+			return fmt.Errorf("--since cannot be combined with pathStyle %s", chunk.PathImportPath)
+		}
+		if len(cfg.RewriteImportPaths) > 0 {
+			// Prior chunks of rebuilt packages are matched by import path.
+			return errors.New("--since cannot be combined with rewriteImportPaths")
+		}
+		prior, err := output.ReadJSONL(absOut)
+		switch {
+		case cfg.UsageExamples > 0:
+			// Any changed caller can change the usage chunk of an unchanged package.
+			logger.Warn("usageExamples needs every package; doing a full build")
+		case errors.Is(err, os.ErrNotExist):
+			logger.Warn("no previous output; doing a full build", "path", absOut)
+		case err != nil:
+			return err
+		default:
+			changed, err := gitChangedFiles(root, *since)
+			if err != nil {
+				return err
+			}
+			var rebuild []chunk.PackageSource
+			if rebuild, kept, incremental = sinceSources(sources, prior, changed); incremental {
+				fmt.Printf("re-chunking %d of %d package(s) changed since %s\n", len(rebuild), len(sources), *since)
+				sources = rebuild
+			} else {
+				logger.Warn("go.mod or go.sum changed; doing a full build", "since", *since)
+			}
+		}
+	}
+	// With --only-changed-modules, modules whose version matches the
+	// previous versions file keep their chunks from the previous output.
+	var unchangedModules map[string]bool
+	if *onlyChangedModules {
+		switch {
+		case *versions == "":
+			return errors.New("--only-changed-modules needs --versions to compare against")
+		case *since != "":
+			return errors.New("--only-changed-modules and --since cannot be combined")
+		case stream != nil:
+			return errors.New("--only-changed-modules and --stream cannot be combined")
+		case (cfg.Format != "" && cfg.Format != ragpack.FormatJSONL) || perPackage || *splitByKind:
+			return fmt.Errorf("--only-changed-modules needs a single %s output file to merge into", ragpack.FormatJSONL)
+		case *maxChunks > 0:
+			return errors.New("--only-changed-modules cannot be combined with --max-chunks")
+		case len(cfg.RewriteImportPaths) > 0:
+			// Kept chunks are matched by their module and import paths.
+			return errors.New("--only-changed-modules cannot be combined with rewriteImportPaths")
+		}
+		absVersions := resolvePath(root, *versions)
+		prev, lockErr := output.ReadLockfile(absVersions)
+		prior, err := output.ReadJSONL(absOut)
+		switch {
+		case cfg.UsageExamples > 0:
+			logger.Warn("usageExamples needs every package; doing a full build")
+		case errors.Is(lockErr, os.ErrNotExist):
+			logger.Warn("no previous versions file; doing a full build", "path", absVersions)
+		case lockErr != nil:
+			return lockErr
+		case errors.Is(err, os.ErrNotExist):
+			logger.Warn("no previous output; doing a full build", "path", absOut)
+		case err != nil:
+			return err
+		default:
+			lock := lockfile(project, cfg)
+			var rebuild []chunk.PackageSource
+			rebuild, kept, unchangedModules = changedModuleSources(sources, prior, lock, prev)
+			changed := 0
+			for _, mod := range lock.Modules {
+				if !unchangedModules[mod.Path] {
+					changed++
+				}
+			}
+			fmt.Printf("re-chunking %d of %d package(s): the project and %d changed module(s)\n", len(rebuild), len(sources), changed)
+			sources = rebuild
+		}
+	}
+	logger.Debug("packages selected", "count", len(sources))
+	if cfg.MaxPackages > 0 && len(sources) > cfg.MaxPackages && !*force {
+		return fmt.Errorf("%d packages selected, more than maxPackages (%d); narrow the selection or pass --force", len(sources), cfg.MaxPackages)
+	}
+
+	var chunks []chunk.Chunk
+	if stream != nil {
+		chunks, err = stream.finish(sources)
+	} else {
+		chunks, err = chunk.Build(sources, opts)
+	}
+	if err != nil {
 		return err
 	}
+	merge := len(kept) > 0
+	chunks = append(kept, chunks...)
+	if cfg.ModuleInfo && !incremental {
+		// Chunks kept by --since already hold module-info, which only a
+		// go.mod change (and so a full build) can make stale; those kept by
+		// --only-changed-modules hold it for the unchanged modules.
+		infos, err := ragpack.ModuleInfo(project, cfg, opts)
+		if err != nil {
+			return err
+		}
+		infos = slices.DeleteFunc(infos, func(ch chunk.Chunk) bool { return unchangedModules[ch.Metadata.ModulePath] })
+		chunks = append(chunks, infos...)
+		merge = merge || len(infos) > 0
+	}
+	if merge {
+		chunk.Sort(chunks, opts)
+	}
+	if idTemplate != nil {
+		if err := idTemplate.Apply(chunks); err != nil {
+			return err
+		}
+	}
+	for _, name := range opts.OnlySymbols.Unmatched() {
+		logger.Warn("--only-symbols name matched no declaration", "name", name)
+	}
+	if *maxChunks > 0 && len(chunks) > *maxChunks {
+		fmt.Printf("dropped %d of %d chunk(s) over --max-chunks %d\n", len(chunks)-*maxChunks, len(chunks), *maxChunks)
+		chunks = chunk.Truncate(chunks, *maxChunks, *prioritize)
+	}
+
+	if *testCoverage || cfg.CoverProfile != "" {
+		profilePath := resolvePath(root, cfg.CoverProfile)
+		if *testCoverage {
+			if profilePath, err = runTestCoverage(root); err != nil {
+				return err
+			}
+			defer os.Remove(profilePath)
+		}
+		profile, err := chunk.ParseCoverProfile(profilePath)
+		if err != nil {
+			return err
+		}
+		chunk.ApplyCoverProfile(chunks, profile)
+	}
+
+	if embedder != nil {
+		if err := embedChunks(embedder, chunks, cfg.EmbedBatchSize); err != nil {
+			return err
+		}
+	}
+
+	var outputs []string
+	if perPackage {
+		files, err := ragpack.WritePerPackage(absOut, outputExt, chunks, writeOutput)
+		if err != nil {
+			return err
+		}
+		outputs = []string{absOut}
+		fmt.Printf("wrote %d chunks to %d package file(s) under %s\n", len(chunks), files, absOut)
+	} else if *splitByKind {
+		files, err := writeBySource(absOut, outputExt, chunks, writeOutput)
+		if err != nil {
+			return err
+		}
+		outputs = files
+		fmt.Printf("wrote %d chunks to %s\n", len(chunks), strings.Join(files, ", "))
+	} else {
+		if err := writeOutput(absOut, chunks); err != nil {
+			return err
+		}
+		outputs = []string{absOut}
+		fmt.Printf("wrote %d chunks to %s\n", len(chunks), absOut)
+	}
+	if skippedFiles > 0 {
+		logger.Warn("skipped files with errors", "count", skippedFiles)
+	}
+	if n := opts.Coverage.ExcludedFor(chunk.ExcludedUndocumented); n > 0 {
+		fmt.Printf("skipped %d undocumented declaration(s)\n", n)
+	}
+
+	if *symbolIndex != "" {
+		absIndex := resolvePath(root, *symbolIndex)
+		if err := output.WriteSymbolIndex(absIndex, chunks); err != nil {
+			return err
+		}
+		fmt.Printf("wrote symbol index to %s\n", absIndex)
+	}
+
+	if *lookupIndex != "" {
+		absLookup := resolvePath(root, *lookupIndex)
+		if err := output.WriteIndex(absLookup, chunks); err != nil {
+			return err
+		}
+		fmt.Printf("wrote symbol lookup index to %s\n", absLookup)
+	}
+
+	if *coverageReport != "" {
+		absCoverage := resolvePath(root, *coverageReport)
+		if err := output.WriteCoverage(absCoverage, opts.Coverage); err != nil {
+			return err
+		}
+		fmt.Printf("indexed %d of %d declarations; coverage report written to %s\n", opts.Coverage.Emitted, opts.Coverage.Total, absCoverage)
+	}
+
+	if *sqlitePath != "" {
+		absSQLite := resolvePath(root, *sqlitePath)
+		if err := output.WriteSQLite(absSQLite, chunks); err != nil {
+			return err
+		}
+		fmt.Printf("wrote SQLite database to %s\n", absSQLite)
+	}
+
+	if *relations != "" {
+		absRelations := resolvePath(root, *relations)
+		if err := output.WriteRelations(absRelations, chunks); err != nil {
+			return err
+		}
+		fmt.Printf("wrote relations to %s\n", absRelations)
+	}
+
+	if *versions != "" {
+		absVersions := resolvePath(root, *versions)
+		lock := lockfile(project, cfg)
+		if prev, err := output.ReadLockfile(absVersions); err == nil {
+			if changes := lock.Changes(prev); len(changes) > 0 {
+				logger.Warn("module versions changed since the last build; re-index any stores built from the previous output", "changes", strings.Join(changes, "; "))
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("reading module versions", "err", err)
+		}
+		if err := output.WriteLockfile(absVersions, lock); err != nil {
+			return err
+		}
+		fmt.Printf("wrote module versions to %s\n", absVersions)
+	}
 
-	fmt.Printf("wrote %d chunks to %s\n", len(chunks), absOut)
+	if *reportJSON != "" {
+		absReport := resolvePath(root, *reportJSON)
+		report := output.NewBuildReport(chunks, outputs, time.Since(start), *warnings)
+		if err := output.WriteBuildReport(absReport, report); err != nil {
+			return err
+		}
+		fmt.Printf("wrote build report to %s\n", absReport)
+	}
 	return nil
 }
 
-func resolvePath(root, p string) string {
-	if filepath.IsAbs(p) {
-		return p
+// runTestCoverage runs the project's tests with a coverage profile written to
+// a temp file and returns its path; go test output goes to stderr. Failing
+// tests still produce a profile, so they only warn.
+func runTestCoverage(root string) (string, error) {
+	f, err := os.CreateTemp("", "go-rag-pack-cover-*.out")
+	if err != nil {
+		return "", err
 	}
-	return filepath.Join(root, p)
+	f.Close()
+	cmd := exec.Command("go", "test", "-coverprofile="+f.Name(), "./...")
+	cmd.Dir = root
+	cmd.Env = discover.GoEnv()
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if info, statErr := os.Stat(f.Name()); statErr != nil || info.Size() == 0 {
+			os.Remove(f.Name())
+			return "", fmt.Errorf("go test -coverprofile: %w", err)
+		}
+		logger.Warn("go test -coverprofile failed; using the partial profile", "err", err)
+	}
+	return f.Name(), nil
 }
 
-func loadOrDefault(root, configPath string) (config.Config, error) {
-	path := resolvePath(root, configPath)
-	cfg, err := config.Load(path)
+// embedChunks sets each chunk's Vector from e, batching requests and
+// reporting progress on stderr.
+func embedChunks(e embed.Embedder, chunks []chunk.Chunk, batchSize int) error {
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = ch.Text
+	}
+	batcher := embed.Batcher{
+		Embedder:  e,
+		BatchSize: batchSize,
+		Retries:   embed.DefaultRetries,
+		Progress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rembedded %d/%d chunks", done, total)
+			if done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		},
+	}
+	vectors, err := batcher.Embed(context.Background(), texts)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			cfg = config.Default(root)
-			cfg.LastProjectRoot = root
-			return cfg, nil
+		return err
+	}
+	for i := range chunks {
+		chunks[i].Vector = vectors[i]
+	}
+	return nil
+}
+
+// sourceFileNames maps each source kind to the name writeBySource puts
+// between the output path's base and extension.
+var sourceFileNames = map[string]string{
+	string(chunk.SourceProject):    "project",
+	string(chunk.SourceStdlib):     "stdlib",
+	string(chunk.SourceThirdParty): "thirdparty",
+}
+
+// writeBySource partitions chunks by Metadata.Source and writes each
+// partition with write next to outPath, so rag/docs.jsonl becomes
+// rag/docs.project.jsonl, rag/docs.stdlib.jsonl and rag/docs.thirdparty.jsonl.
+// Empty partitions get no file. It returns the paths it wrote, in that order.
+func writeBySource(outPath, defaultExt string, chunks []chunk.Chunk, write func(string, []chunk.Chunk) error) ([]string, error) {
+	groups := make(map[string][]chunk.Chunk)
+	for _, ch := range chunks {
+		groups[ch.Metadata.Source] = append(groups[ch.Metadata.Source], ch)
+	}
+	var written []string
+	for _, kind := range []chunk.SourceKind{chunk.SourceProject, chunk.SourceStdlib, chunk.SourceThirdParty} {
+		group := groups[string(kind)]
+		if len(group) == 0 {
+			continue
+		}
+		path := sourceFilePath(outPath, defaultExt, kind)
+		if err := write(path, group); err != nil {
+			return written, err
 		}
-		return config.Config{}, err
+		written = append(written, path)
 	}
-	if cfg.OutputPath == "" {
-		cfg.OutputPath = filepath.Join("rag", "go_docs.jsonl")
+	return written, nil
+}
+
+// sourceFilePath returns the --split-by-kind file for kind beside outPath,
+// keeping a .gz suffix last and using defaultExt when outPath has none.
+func sourceFilePath(outPath, defaultExt string, kind chunk.SourceKind) string {
+	base, gz := strings.CutSuffix(outPath, ".gz")
+	ext := filepath.Ext(base)
+	if ext == "" {
+		ext = defaultExt
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	if gz {
+		ext += ".gz"
 	}
-	return cfg, nil
+	return base + "." + sourceFileNames[string(kind)] + ext
 }
 
-func dedupeSources(sources []chunk.PackageSource) []chunk.PackageSource {
-	if len(sources) <= 1 {
-		return sources
+// gitChangedFiles lists the files that differ between ref and the working
+// tree, relative to root.
+func gitChangedFiles(root, ref string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--relative", ref, "--")
+	cmd.Dir = root
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-only %s: %w (%s)", ref, err, strings.TrimSpace(stderr.String()))
 	}
-	type key struct {
-		importPath string
-		dir        string
+	return splitLines(string(out)), nil
+}
+
+// sinceSources narrows sources to the project packages with changed .go
+// files and returns the prior chunks to keep: everything outside those
+// packages, minus chunks from changed files (which covers deleted files and
+// packages). It reports false, asking for a full build, when go.mod or go.sum
+// changed, since dependency chunks may then be stale too.
+func sinceSources(sources []chunk.PackageSource, prior []chunk.Chunk, changed []string) ([]chunk.PackageSource, []chunk.Chunk, bool) {
+	changedFiles := make(map[string]bool, len(changed))
+	changedDirs := make(map[string]bool)
+	for _, file := range changed {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum":
+			return sources, nil, false
+		}
+		if strings.HasSuffix(file, ".go") {
+			changedFiles[filepath.ToSlash(file)] = true
+			changedDirs[filepath.FromSlash(filepath.Dir(file))] = true
+		}
 	}
-	seen := make(map[key]chunk.PackageSource)
+
+	var rebuild []chunk.PackageSource
+	rebuilt := make(map[string]bool)
 	for _, src := range sources {
-		k := key{importPath: src.ImportPath, dir: src.Dir}
-		// If duplicates exist, prefer project sources, then third-party, then stdlib.
-		if existing, ok := seen[k]; ok {
-			order := func(k chunk.SourceKind) int {
-				switch k {
-				case chunk.SourceProject:
-					return 0
-				case chunk.SourceThirdParty:
-					return 1
-				case chunk.SourceStdlib:
-					return 2
-				default:
-					return 3
-				}
-			}
-			if order(src.Kind) < order(existing.Kind) {
-				seen[k] = src
-			}
+		if src.Kind != chunk.SourceProject {
+			continue
+		}
+		rel, err := filepath.Rel(src.ModuleDir, src.Dir)
+		if err == nil && changedDirs[rel] {
+			rebuild = append(rebuild, src)
+			rebuilt[src.ImportPath] = true
+		}
+	}
+
+	var kept []chunk.Chunk
+	for _, ch := range prior {
+		md := ch.Metadata
+		if rebuilt[md.ImportPath] {
 			continue
 		}
-		seen[k] = src
+		if md.Source == string(chunk.SourceProject) && changedFiles[md.Path] {
+			continue
+		}
+		kept = append(kept, ch)
 	}
+	return rebuild, kept, true
+}
 
-	keys := make([]key, 0, len(seen))
-	for k := range seen {
-		keys = append(keys, k)
+// changedModuleSources narrows sources to those of the project and of
+// modules whose version or replacement differs between prev and lock, with
+// the stdlib counting as module "std" that changes with the Go version. It
+// returns the prior chunks to keep, those of the still-selected packages and
+// module-info of unchanged modules, and the set of unchanged modules.
+func changedModuleSources(sources []chunk.PackageSource, prior []chunk.Chunk, lock, prev output.Lockfile) ([]chunk.PackageSource, []chunk.Chunk, map[string]bool) {
+	before := make(map[string]string, len(prev.Modules))
+	for _, mod := range prev.Modules {
+		before[mod.Path] = mod.String()
 	}
-	slices.SortFunc(keys, func(a, b key) int {
-		if cmp := strings.Compare(a.importPath, b.importPath); cmp != 0 {
-			return cmp
+	unchanged := make(map[string]bool)
+	for _, mod := range lock.Modules {
+		if s, ok := before[mod.Path]; ok && s == mod.String() {
+			unchanged[mod.Path] = true
 		}
-		return strings.Compare(a.dir, b.dir)
-	})
+	}
+	if lock.GoVersion == prev.GoVersion {
+		unchanged["std"] = true
+	}
 
-	deduped := make([]chunk.PackageSource, 0, len(keys))
-	for _, k := range keys {
-		deduped = append(deduped, seen[k])
+	var rebuild []chunk.PackageSource
+	selected := make(map[string]bool, len(sources))
+	for _, src := range sources {
+		selected[src.ImportPath] = true
+		if src.Kind == chunk.SourceProject || !unchanged[src.ModulePath] {
+			rebuild = append(rebuild, src)
+		}
 	}
-	return deduped
-}
 
-func scanModulePackages(module discover.Module) ([]discover.Package, error) {
-	var packages []discover.Package
-	err := filepath.WalkDir(module.Dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	var kept []chunk.Chunk
+	for _, ch := range prior {
+		md := ch.Metadata
+		if md.Source == string(chunk.SourceProject) || !unchanged[md.ModulePath] {
+			continue
 		}
-		if !d.IsDir() {
-			return nil
+		if md.Kind == "module-info" || selected[md.ImportPath] {
+			kept = append(kept, ch)
 		}
+	}
+	return rebuild, kept, unchanged
+}
 
-		name := d.Name()
-		switch name {
-		case "vendor", "testdata":
-			return filepath.SkipDir
+// splitLines splits s on newlines, dropping empty lines.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
 		}
-		if strings.HasPrefix(name, ".") {
-			return filepath.SkipDir
+	}
+	return lines
+}
+
+// lockfile records the Go version, main module and selected modules of a
+// build, resolved against the project's module graph.
+func lockfile(project discover.Project, cfg config.Config) output.Lockfile {
+	lock := output.Lockfile{
+		GoVersion:  runtime.Version(),
+		MainModule: lockedModule(project.MainModule),
+	}
+	byPath := make(map[string]discover.Module, len(project.AllModules))
+	for _, mod := range project.AllModules {
+		byPath[mod.Path] = mod
+	}
+	seen := make(map[string]bool)
+	for _, path := range slices.Concat(cfg.SelectedModules, cfg.ManualModules) {
+		mod, ok := byPath[path]
+		if !ok || seen[path] {
+			continue
 		}
+		seen[path] = true
+		lock.Modules = append(lock.Modules, lockedModule(mod))
+	}
+	return lock
+}
+
+func lockedModule(mod discover.Module) output.LockedModule {
+	locked := output.LockedModule{Path: mod.Path, Version: mod.Version}
+	if mod.Replace != nil {
+		locked.Replace = &output.LockedModule{Path: mod.Replace.Path, Version: mod.Replace.Version}
+	}
+	return locked
+}
+
+func runClean(args []string) error {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	setupLog := addLogFlags(fs)
+	configPath := fs.String("config", "", "config file path (default .go-rag-pack.json, or an existing .yaml/.yml)")
+	strictConfig := fs.Bool("strict-config", true, "reject unknown config keys and values; =false ignores them")
+	force := fs.Bool("force", false, "remove without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := setupLog(); err != nil {
+		return err
+	}
+
+	root, err := projectRoot(*configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := ragpack.LoadConfig(root, *configPath, *strictConfig)
+	if err != nil {
+		return err
+	}
 
-		hasGo := false
-		entries, err := os.ReadDir(path)
+	outPath := resolvePath(root, cfg.OutputPath)
+	dirOutput := ragpack.IsDirOutput(cfg.OutputPath)
+	_, ext, err := ragpack.WriterFor(cfg.Format)
+	if err != nil {
+		return err
+	}
+	targets, err := cleanTargets(root, outPath, ext, dirOutput)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("nothing to clean")
+		return nil
+	}
+
+	if !*force {
+		ok, err := ui.Confirm(fmt.Sprintf("Remove %s?", strings.Join(targets, ", ")))
 		if err != nil {
 			return err
 		}
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, path := range targets {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s\n", path)
+	}
+
+	// Drop directories the removal left empty: the package directories of a
+	// per-package output up to the output directory itself, or the directory
+	// holding an output file.
+	var dirs []string
+	if dirOutput {
+		for _, path := range targets {
+			for dir := filepath.Dir(path); withinRoot(outPath, dir); dir = filepath.Dir(dir) {
+				dirs = append(dirs, dir)
 			}
-			fileName := entry.Name()
-			if !strings.HasSuffix(fileName, ".go") {
-				continue
+		}
+		// Deepest first, so parents are empty by the time they are tried.
+		slices.SortFunc(dirs, func(a, b string) int {
+			if len(a) != len(b) {
+				return len(b) - len(a)
 			}
-			if shouldSkipManualFile(fileName) {
-				continue
+			return strings.Compare(a, b)
+		})
+		dirs = slices.Compact(dirs)
+	} else {
+		dirs = []string{filepath.Dir(outPath)}
+	}
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == filepath.Clean(root) || !withinRoot(root, dir) {
+			continue
+		}
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return err
 			}
-			hasGo = true
-			break
+			fmt.Printf("removed %s\n", dir)
 		}
-		if !hasGo {
-			return nil
+	}
+	return nil
+}
+
+// cleanTargets lists the existing generated files that belong to the output
+// at outPath: for a per-package directory output, the package files with
+// extension ext (or ext.gz) that WritePerPackage writes, and otherwise the
+// output file and the files --split-by-kind writes beside it. It refuses
+// outputs outside root or at root itself.
+func cleanTargets(root, outPath, ext string, dir bool) ([]string, error) {
+	if filepath.Clean(outPath) == filepath.Clean(root) {
+		return nil, fmt.Errorf("refusing to clean %s: the output path is the project root", outPath)
+	}
+	if !withinRoot(root, outPath) {
+		return nil, fmt.Errorf("refusing to remove %s: outside project root %s", outPath, root)
+	}
+	if dir {
+		return ragpack.PackageFiles(outPath, ext+".gz", ext)
+	}
+	candidates := []string{outPath}
+	for _, kind := range []chunk.SourceKind{chunk.SourceProject, chunk.SourceStdlib, chunk.SourceThirdParty} {
+		candidates = append(candidates, sourceFilePath(outPath, ".jsonl", kind))
+	}
+	var targets []string
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			targets = append(targets, path)
 		}
+	}
+	return targets, nil
+}
 
-		rel, err := filepath.Rel(module.Dir, path)
-		if err != nil {
-			return err
+// withinRoot reports whether path is root itself or lies beneath it.
+func withinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// overridesRequireDoc reports whether a module override turns on
+// requireDoc, whose skipped declarations the build counts.
+func overridesRequireDoc(cfg config.Config) bool {
+	for _, ov := range cfg.ModuleOverrides {
+		if ov.RequireDoc != nil && *ov.RequireDoc {
+			return true
 		}
-		importPath := module.Path
-		if rel != "." {
-			importPath = module.Path + "/" + filepath.ToSlash(rel)
-		}
-		packages = append(packages, discover.Package{
-			ImportPath: importPath,
-			Dir:        path,
-			Name:       filepath.Base(path),
-			Module: &discover.Module{
-				Path:    module.Path,
-				Version: module.Version,
-				Dir:     module.Dir,
-			},
-		})
-		return nil
-	})
+	}
+	return false
+}
+
+// gitHead returns the commit checked out in the repository containing root.
+func gitHead(root string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = root
+	out, err := cmd.Output()
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("git rev-parse HEAD: %w", err)
 	}
-	return packages, nil
+	return strings.TrimSpace(string(out)), nil
 }
 
-func shouldSkipManualFile(name string) bool {
-	switch {
-	case strings.HasSuffix(name, "_test.go"),
-		strings.HasSuffix(name, "_mock.go"),
-		strings.HasSuffix(name, "_generated.go"),
-		strings.Contains(name, ".pb.go"),
-		strings.Contains(name, "_pb2.go"):
-		return true
-	default:
-		return false
+// stringList is a flag.Value collecting every use of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// streamBuild chunks the project's packages as go list reports them, ahead
+// of the rest of the build's sources.
+type streamBuild struct {
+	sources chan chunk.PackageSource
+	listed  chan error
+	built   chan streamResult
+	// seen records the streamed packages; it is complete once listed has
+	// been received from.
+	seen map[string]struct{}
+}
+
+type streamResult struct {
+	chunks []chunk.Chunk
+	err    error
+}
+
+func startStream(root string, opts chunk.Options) *streamBuild {
+	sb := &streamBuild{
+		sources: make(chan chunk.PackageSource),
+		listed:  make(chan error, 1),
+		built:   make(chan streamResult, 1),
+		seen:    make(map[string]struct{}),
+	}
+	go func() {
+		chunks, err := chunk.BuildStream(sb.sources, opts)
+		sb.built <- streamResult{chunks: chunks, err: err}
+	}()
+	go func() {
+		pkgs := make(chan discover.Package)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- discover.StreamPackages(root, pkgs)
+			close(pkgs)
+		}()
+		for pkg := range pkgs {
+			sb.seen[pkg.ImportPath+" "+pkg.Dir] = struct{}{}
+			sb.sources <- chunk.PackageSource{
+				ModulePath:    pkg.Module.Path,
+				ModuleVersion: pkg.Module.Version,
+				ModuleDir:     root,
+				ImportPath:    pkg.ImportPath,
+				Dir:           pkg.Dir,
+				Kind:          chunk.SourceProject,
+			}
+		}
+		sb.listed <- <-errc
+	}()
+	return sb
+}
+
+// finish feeds the remaining sources, skipping packages already streamed,
+// and returns the chunks of the whole build.
+func (sb *streamBuild) finish(rest []chunk.PackageSource) ([]chunk.Chunk, error) {
+	listErr := <-sb.listed
+	if listErr == nil {
+		for _, src := range rest {
+			if _, ok := sb.seen[src.ImportPath+" "+src.Dir]; !ok {
+				sb.sources <- src
+			}
+		}
+	}
+	close(sb.sources)
+	res := <-sb.built
+	if listErr != nil {
+		return nil, listErr
+	}
+	return res.chunks, res.err
+}
+
+// discoverProject runs cached discovery, warning about each dependency
+// package go list could not load.
+func discoverProject(root string, refresh bool, retries int) (discover.Project, error) {
+	project, err := ragpack.Discover(root, refresh, retries)
+	if err != nil {
+		return project, err
+	}
+	for _, pkg := range project.Skipped {
+		logger.Warn("skipping package that go list could not load", "importPath", pkg.ImportPath, "err", pkg.Error.Err)
+	}
+	return project, nil
+}
+
+// splitList parses a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if v := strings.TrimSpace(part); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func resolvePath(root, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(root, p)
+}
+
+// configFile resolves the --config flag, falling back to the project config
+// found in root.
+func configFile(root, flagValue string) string {
+	if flagValue == "" {
+		return config.Find(root)
+	}
+	return resolvePath(root, flagValue)
+}
+
+// projectRoot returns the directory commands treat as the project root: the
+// nearest directory at or above the working directory holding a project
+// config, so the tool works from any subdirectory. With an explicit
+// --config, or when no config is found, it is the working directory.
+func projectRoot(configPath string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil || configPath != "" {
+		return wd, err
+	}
+	if dir, ok := config.FindUp(wd); ok {
+		if dir != wd {
+			logger.Debug("using project root from parent directory", "root", dir)
+		}
+		return dir, nil
 	}
+	return wd, nil
 }
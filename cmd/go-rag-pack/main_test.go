@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+	"github.com/natedelduca/go-rag-pack/internal/config"
+	"github.com/natedelduca/go-rag-pack/internal/discover"
+	"github.com/natedelduca/go-rag-pack/internal/output"
+	"github.com/natedelduca/go-rag-pack/ragpack"
+)
+
+func TestLockfileReflectsDiscoveredModules(t *testing.T) {
+	project := discover.Project{
+		MainModule: discover.Module{Path: "example.com/m", Main: true},
+		AllModules: []discover.Module{
+			{Path: "example.com/m", Main: true},
+			{Path: "example.com/a", Version: "v1.2.0"},
+			{Path: "example.com/b", Version: "v0.3.1", Replace: &discover.Module{Path: "../b"}},
+			{Path: "example.com/unused", Version: "v2.0.0"},
+		},
+	}
+	cfg := config.Config{
+		SelectedModules: []string{"example.com/b", "example.com/a", "example.com/missing"},
+		ManualModules:   []string{"example.com/a"},
+	}
+
+	got := lockfile(project, cfg)
+	want := output.Lockfile{
+		GoVersion:  runtime.Version(),
+		MainModule: output.LockedModule{Path: "example.com/m"},
+		Modules: []output.LockedModule{
+			{Path: "example.com/b", Version: "v0.3.1", Replace: &output.LockedModule{Path: "../b"}},
+			{Path: "example.com/a", Version: "v1.2.0"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lockfile = %+v, want %+v", got, want)
+	}
+}
+
+func TestCleanTargets(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"rag/go_docs.jsonl",
+		"rag/go_docs.project.jsonl",
+		"pkgs/example.com/m.jsonl",
+		"pkgs/example.com/m/sub.jsonl.gz",
+		"pkgs/example.com/m/notes.txt",
+		"pkgs/example.com/m/bad name.jsonl",
+		"main.go",
+	}
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    []string
+		wantErr string
+	}{
+		{name: "project root as directory", output: "./", wantErr: "project root"},
+		{name: "project root", output: ".", wantErr: "project root"},
+		{name: "outside root", output: "../elsewhere/", wantErr: "outside project root"},
+		{name: "output file", output: "rag/go_docs.jsonl", want: []string{"rag/go_docs.jsonl", "rag/go_docs.project.jsonl"}},
+		{name: "per-package directory", output: "pkgs/", want: []string{"pkgs/example.com/m.jsonl", "pkgs/example.com/m/sub.jsonl.gz"}},
+		{name: "missing directory", output: "none/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanTargets(root, resolvePath(root, tt.output), ".jsonl", ragpack.IsDirOutput(tt.output))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rel []string
+			for _, path := range got {
+				r, _ := filepath.Rel(root, path)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			slices.Sort(rel)
+			if !slices.Equal(rel, tt.want) {
+				t.Errorf("targets = %v, want %v", rel, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinceSources(t *testing.T) {
+	project := func(importPath, dir string) chunk.PackageSource {
+		return chunk.PackageSource{ModulePath: "example.com/m", ModuleDir: "/repo", ImportPath: importPath, Dir: filepath.Join("/repo", dir), Kind: chunk.SourceProject}
+	}
+	sources := []chunk.PackageSource{
+		project("example.com/m/p", "p"),
+		project("example.com/m/q", "q"),
+		{ModulePath: "example.com/dep", ModuleDir: "/cache/dep", ImportPath: "example.com/dep", Dir: "/cache/dep", Kind: chunk.SourceThirdParty},
+	}
+	prior := []chunk.Chunk{
+		{ID: "p/a.go:A", Metadata: chunk.Metadata{Path: "p/a.go", ImportPath: "example.com/m/p", Source: "project"}},
+		{ID: "p/b.go:B", Metadata: chunk.Metadata{Path: "p/b.go", ImportPath: "example.com/m/p", Source: "project"}},
+		{ID: "p/gone.go:Gone", Metadata: chunk.Metadata{Path: "p/gone.go", ImportPath: "example.com/m/p", Source: "project"}},
+		{ID: "q/q.go:Q", Metadata: chunk.Metadata{Path: "q/q.go", ImportPath: "example.com/m/q", Source: "project"}},
+		{ID: "dep.go:D", Metadata: chunk.Metadata{Path: "dep.go", ImportPath: "example.com/dep", Source: "third-party"}},
+	}
+
+	tests := []struct {
+		name        string
+		changed     []string
+		wantRebuild []string
+		wantKept    []string
+		wantOK      bool
+	}{
+		{
+			name:        "rebuilt package drops its unchanged files too",
+			changed:     []string{"p/a.go", "README.md"},
+			wantRebuild: []string{"example.com/m/p"},
+			wantKept:    []string{"q/q.go:Q", "dep.go:D"},
+			wantOK:      true,
+		},
+		{
+			name:     "nothing changed",
+			changed:  []string{"docs/guide.md"},
+			wantKept: []string{"p/a.go:A", "p/b.go:B", "p/gone.go:Gone", "q/q.go:Q", "dep.go:D"},
+			wantOK:   true,
+		},
+		{
+			name:     "deleted package",
+			changed:  []string{"r/r.go"},
+			wantKept: []string{"p/a.go:A", "p/b.go:B", "p/gone.go:Gone", "q/q.go:Q", "dep.go:D"},
+			wantOK:   true,
+		},
+		{
+			name:    "go.sum forces a full build",
+			changed: []string{"p/a.go", "go.sum"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebuild, kept, ok := sinceSources(sources, prior, tt.changed)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			var gotRebuild, gotKept []string
+			for _, src := range rebuild {
+				gotRebuild = append(gotRebuild, src.ImportPath)
+			}
+			for _, ch := range kept {
+				gotKept = append(gotKept, ch.ID)
+			}
+			if !slices.Equal(gotRebuild, tt.wantRebuild) {
+				t.Errorf("rebuild = %v, want %v", gotRebuild, tt.wantRebuild)
+			}
+			if !slices.Equal(gotKept, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", gotKept, tt.wantKept)
+			}
+			// Rebuilt packages must not also keep prior chunks, or the merged
+			// output would hold their IDs twice.
+			for _, ch := range kept {
+				if slices.Contains(gotRebuild, ch.Metadata.ImportPath) {
+					t.Errorf("kept %s from rebuilt package %s", ch.ID, ch.Metadata.ImportPath)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	base := newLogger(&logs, slog.LevelError, false)
+	first, firstWarnings := recordWarnings(base)
+	first.With("module", "example.com/a").Warn("first build")
+	first.Info("not recorded")
+
+	// A second build wraps the original logger again, not the first wrapper,
+	// so it starts with no warnings and records only its own.
+	second, secondWarnings := recordWarnings(base)
+	second.Error("second build", "path", "x.go")
+
+	want := []output.ReportWarning{{Level: "warn", Message: "first build", Fields: map[string]string{"module": "example.com/a"}}}
+	if !reflect.DeepEqual(*firstWarnings, want) {
+		t.Errorf("first build warnings = %+v, want %+v", *firstWarnings, want)
+	}
+	want = []output.ReportWarning{{Level: "error", Message: "second build", Fields: map[string]string{"path": "x.go"}}}
+	if !reflect.DeepEqual(*secondWarnings, want) {
+		t.Errorf("second build warnings = %+v, want %+v", *secondWarnings, want)
+	}
+	if strings.Count(logs.String(), "second build") != 1 || strings.Contains(logs.String(), "first build") {
+		t.Errorf("base logger output = %q, want only the error once", logs.String())
+	}
+}
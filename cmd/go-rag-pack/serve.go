@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+	"github.com/natedelduca/go-rag-pack/ragpack"
+)
+
+// serveShutdownTimeout bounds how long in-flight requests may take to
+// finish after an interrupt.
+const serveShutdownTimeout = 5 * time.Second
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	setupLog := addLogFlags(fs)
+	configPath := fs.String("config", "", "config file path (default .go-rag-pack.json, or an existing .yaml/.yml)")
+	strictConfig := fs.Bool("strict-config", true, "reject unknown config keys and values; =false ignores them")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	refresh := fs.Bool("refresh", false, "ignore cached go list output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := setupLog(); err != nil {
+		return err
+	}
+
+	root, err := projectRoot(*configPath)
+	if err != nil {
+		return err
+	}
+	cfg, err := ragpack.LoadConfig(root, *configPath, *strictConfig)
+	if err != nil {
+		return err
+	}
+	opts, err := ragpack.ChunkOptions(cfg)
+	if err != nil {
+		return err
+	}
+	project, err := discoverProject(root, *refresh, cfg.GoListRetries)
+	if err != nil {
+		return err
+	}
+
+	// Every package discovery found can be served: the project's, the
+	// stdlib packages it imports and those of its dependencies.
+	sources := make(map[string]chunk.PackageSource)
+	for _, pkg := range project.StdlibPackages {
+		sources[pkg.ImportPath] = ragpack.SourceOf(pkg, root)
+	}
+	for _, mu := range project.ThirdParty {
+		for _, pkg := range mu.Packages {
+			mod := mu.Module
+			pkg.Module = &mod
+			sources[pkg.ImportPath] = ragpack.SourceOf(pkg, root)
+		}
+	}
+	for _, pkg := range project.InternalPackages {
+		sources[pkg.ImportPath] = chunk.PackageSource{
+			ModulePath:    project.MainModule.Path,
+			ModuleVersion: project.MainModule.Version,
+			ModuleDir:     project.Root,
+			ImportPath:    pkg.ImportPath,
+			Dir:           pkg.Dir,
+			Kind:          chunk.SourceProject,
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("GET /chunks", chunksHandler(sources, opts))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: mux}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	logger.Info("serving chunks; press Ctrl-C to stop", "packages", len(sources), "addr", *addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("stopped serving")
+	return nil
+}
+
+// chunksHandler serves GET /chunks?importPath=... by chunking that package
+// on each request, so edits show up without a rebuild. Builds run one at a
+// time because Options carries shared state such as the OnlySymbols
+// allowlist.
+func chunksHandler(sources map[string]chunk.PackageSource, opts chunk.Options) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		importPath := r.URL.Query().Get("importPath")
+		if importPath == "" {
+			http.Error(w, "missing importPath parameter", http.StatusBadRequest)
+			return
+		}
+		src, ok := sources[importPath]
+		if !ok {
+			http.Error(w, fmt.Sprintf("package %s not found in the project's dependencies", importPath), http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		chunks, err := chunk.Build([]chunk.PackageSource{src}, opts)
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if chunks == nil {
+			chunks = []chunk.Chunk{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(chunks); err != nil {
+			logger.Warn("writing response", "err", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the tree must stay quiet before a rebuild starts,
+// so saving several files (or a formatter rewriting them) triggers one build.
+const watchDebounce = 300 * time.Millisecond
+
+// watchBuild runs build once, then again whenever a .go file changes under
+// the project root or a local replace directory, until interrupted. Build
+// errors are reported and watching continues.
+func watchBuild(root string, build func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := build(); err != nil {
+		logger.Error(err.Error())
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := []string{root}
+	if project, err := discoverProject(root, false, 0); err != nil {
+		logger.Warn("replace directories not watched", "err", err)
+	} else {
+		for _, mod := range project.AllModules {
+			// Local replacements have no version; module cache copies never change.
+			if rep := mod.Replace; rep != nil && rep.Version == "" && mod.Dir != "" {
+				dirs = append(dirs, mod.Dir)
+			}
+		}
+	}
+	for _, dir := range dirs {
+		if err := watchTree(watcher, dir); err != nil {
+			return err
+		}
+	}
+	logger.Info("watching for .go changes; press Ctrl-C to stop", "dirs", len(watcher.WatchList()))
+
+	var timer <-chan time.Time
+	changed := make(map[string]struct{})
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("stopped watching")
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn("watch", "err", err)
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := watchTree(watcher, ev.Name); err != nil {
+						logger.Warn("watch", "path", ev.Name, "err", err)
+					}
+					continue
+				}
+			}
+			if !strings.HasSuffix(ev.Name, ".go") || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[ev.Name] = struct{}{}
+			timer = time.After(watchDebounce)
+		case <-timer:
+			timer = nil
+			logger.Info("files changed; rebuilding", "count", len(changed))
+			clear(changed)
+			start := time.Now()
+			if err := build(); err != nil {
+				logger.Error(err.Error())
+				continue
+			}
+			logger.Info("rebuilt", "took", time.Since(start).Round(time.Millisecond))
+		}
+	}
+}
+
+// watchTree adds dir and its subdirectories to watcher, skipping the
+// directories the go tool ignores: vendor, testdata and dot-directories.
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir {
+			name := d.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+		}
+		return watcher.Add(path)
+	})
+}
+
+// dropFlag returns args without any occurrence of the boolean flag name, in
+// its -name, --name and -name=value forms.
+func dropFlag(args []string, name string) []string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		trimmed := strings.TrimLeft(arg, "-")
+		if arg != trimmed && (trimmed == name || strings.HasPrefix(trimmed, name+"=")) {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package chunk
+
+import "sort"
+
+// Allowlist restricts a build to declarations named exactly: functions and
+// types by name, methods by receiver-qualified name (Server.Serve), fields
+// by Type.Field, and grouped values by any of their names. It records which
+// names were found so callers can report the rest.
+type Allowlist struct {
+	names   map[string]bool
+	matched map[string]bool
+}
+
+// NewAllowlist returns an Allowlist of names, or nil when names is empty.
+func NewAllowlist(names []string) *Allowlist {
+	if len(names) == 0 {
+		return nil
+	}
+	a := &Allowlist{names: make(map[string]bool, len(names)), matched: make(map[string]bool)}
+	for _, name := range names {
+		a.names[name] = true
+	}
+	return a
+}
+
+// allow reports whether any of names is listed and marks the ones that are.
+// A nil Allowlist allows everything.
+func (a *Allowlist) allow(names ...string) bool {
+	if a == nil {
+		return true
+	}
+	ok := false
+	for _, name := range names {
+		if a.names[name] {
+			a.matched[name] = true
+			ok = true
+		}
+	}
+	return ok
+}
+
+// Unmatched returns, sorted, the listed names no declaration matched.
+func (a *Allowlist) Unmatched() []string {
+	if a == nil {
+		return nil
+	}
+	var names []string
+	for name := range a.names {
+		if !a.matched[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
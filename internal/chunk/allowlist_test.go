@@ -0,0 +1,96 @@
+package chunk
+
+import (
+	"slices"
+	"testing"
+)
+
+const allowlistSrc = `package m
+
+// Server serves requests.
+type Server struct{}
+
+// Start starts the server.
+func (s *Server) Start() {}
+
+// Stop stops the server.
+func (s *Server) Stop() {}
+
+// Run runs a server.
+func Run() {}
+
+func helper() {}
+
+const (
+	A = 1
+	B = 2
+)
+`
+
+func TestOnlySymbols(t *testing.T) {
+	tests := []struct {
+		name          string
+		only          []string
+		want          []string
+		wantUnmatched []string
+	}{
+		{
+			name: "function",
+			only: []string{"Run"},
+			want: []string{"a.go:Run"},
+		},
+		{
+			name: "method by receiver",
+			only: []string{"Server.Start"},
+			want: []string{"a.go:Start"},
+		},
+		{
+			name: "type",
+			only: []string{"Server"},
+			want: []string{"a.go:type:Server"},
+		},
+		{
+			name: "mixed with unexported",
+			only: []string{"helper", "Server.Stop", "Run"},
+			want: []string{"a.go:Run", "a.go:Stop", "a.go:helper"},
+		},
+		{
+			name: "grouped value",
+			only: []string{"B"},
+			want: []string{"a.go:const:B"},
+		},
+		{
+			name:          "unmatched reported",
+			only:          []string{"Run", "Start", "Missing"},
+			want:          []string{"a.go:Run"},
+			wantUnmatched: []string{"Missing", "Start"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			only := NewAllowlist(tt.only)
+			chunks := buildFiles(t, map[string]string{"a.go": allowlistSrc}, Options{OnlySymbols: only})
+			var got []string
+			for _, ch := range chunks {
+				got = append(got, ch.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+			if unmatched := only.Unmatched(); !slices.Equal(unmatched, tt.wantUnmatched) {
+				t.Errorf("Unmatched = %v, want %v", unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
+
+func TestNilAllowlist(t *testing.T) {
+	if NewAllowlist(nil) != nil {
+		t.Error("NewAllowlist(nil) != nil")
+	}
+	var a *Allowlist
+	if !a.allow("anything") || a.Unmatched() != nil {
+		t.Error("nil Allowlist should allow everything and report nothing")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 )
@@ -37,6 +38,11 @@ type Chunk struct {
 	ID       string   `json:"id"`
 	Text     string   `json:"text"`
 	Metadata Metadata `json:"metadata"`
+	// Vector is the chunk's embedding, set only when a build embeds chunks.
+	Vector []float32 `json:"vector,omitempty"`
+	// documented records that the declaration had a doc comment, whether or
+	// not it was rendered, for Truncate's prioritizing. It is not written out.
+	documented bool
 }
 
 // Metadata provides AnythingLLM with contextual details on a chunk.
@@ -47,39 +53,416 @@ type Metadata struct {
 	ModulePath    string `json:"module"`
 	ModuleVersion string `json:"moduleVersion,omitempty"`
 	Symbol        string `json:"symbol,omitempty"`
+	Signature     string `json:"signature,omitempty"`
 	Kind          string `json:"kind"`
 	Source        string `json:"source"`
+	// StartLine and EndLine are the 1-based, inclusive source lines the chunk
+	// was extracted from.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// SourceURL links to the chunk's source, built from Options.SourceURLs.
+	SourceURL string `json:"sourceUrl,omitempty"`
+	// ReceiverType is the base type a method is declared on, without pointer
+	// or type parameters, so Server and *Server methods share one value.
+	ReceiverType string `json:"receiverType,omitempty"`
+	// ReceiverKind is "pointer" or "value" for methods.
+	ReceiverKind string `json:"receiverKind,omitempty"`
+	// Implements lists, on type chunks, the interfaces the type or its
+	// pointer implements, as "io.Reader", from Options.Implements.
+	Implements []string `json:"implements,omitempty"`
+	// IsMain is set on every chunk of a package main, so chunks of
+	// executables can be told from those of libraries.
+	IsMain bool `json:"isMain,omitempty"`
+	// PackageDeprecated is set on every chunk of a package whose package
+	// comment carries a "Deprecated:" paragraph.
+	PackageDeprecated bool `json:"packageDeprecated,omitempty"`
+	// Deprecated is set on function, type and value chunks whose doc comment
+	// carries a "Deprecated:" paragraph; DeprecationNote holds its text.
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	DeprecationNote string `json:"deprecationNote,omitempty"`
+	// ContentHash is the first 16 hex digits of the SHA-256 of Text, so
+	// consumers can diff two builds and re-embed only the chunks whose text
+	// changed.
+	ContentHash string `json:"contentHash"`
+	// Part and Parts number the pieces of a declaration split by MaxTokens.
+	Part  int `json:"part,omitempty"`
+	Parts int `json:"parts,omitempty"`
+	// TestPackageKind is set on chunks from _test.go files:
+	// TestPackageInternal for package foo, TestPackageExternal for foo_test.
+	TestPackageKind string `json:"testPackageKind,omitempty"`
+	// SearchKeywords is the symbol name split into lower-case words
+	// ("serve http" for ServeHTTP) for keyword search, when enabled.
+	SearchKeywords string `json:"searchKeywords,omitempty"`
+	// Topic is the category assigned by the first matching Options.Topics rule.
+	Topic string `json:"topic,omitempty"`
+	// FileSymbolCount is how many declarations (functions, methods, type and
+	// const/var specs) the chunk's source file contains, as a density signal
+	// for re-ranking. Package-doc chunks span files and leave it zero.
+	FileSymbolCount int `json:"fileSymbolCount,omitempty"`
+	// Covered and CoveragePct report, for function chunks, whether any
+	// statement ran and what percentage did, from a go test coverage profile.
+	// They are unset when no profile was given or it has no blocks for the
+	// function.
+	Covered     *bool    `json:"covered,omitempty"`
+	CoveragePct *float64 `json:"coveragePct,omitempty"`
+	// Extra holds kind-specific attributes, such as parsed struct tags on field chunks.
+	Extra map[string]string `json:"extra,omitempty"`
 }
 
-// Build walks the provided package sources and returns extracted chunks.
-func Build(sources []PackageSource) ([]Chunk, error) {
+// AllKinds lists every value of Metadata.Kind, as accepted by Options.Kinds.
+// Package-toc shards are built with, and selected by, "package-doc".
+var AllKinds = []string{"function", "type", "const", "var", "field", "file-doc", "package-doc", "directive", "usage", "module-info", "signature", "type-bundle", "native-code", "command"}
+
+// Options controls how declarations are rendered into chunks.
+type Options struct {
+	// DocPolicy toggles doc comment inclusion per chunk kind (function, type,
+	// const, var). Kinds missing from the map keep their doc comments.
+	DocPolicy map[string]bool
+	// InlineDoc starts snippets at the leading doc comment so it is kept with
+	// its original formatting instead of being re-joined above the code.
+	InlineDoc bool
+	// IncludeImports prepends the imports referenced by a function or type
+	// chunk so qualified identifiers like pb.Message can be resolved.
+	IncludeImports bool
+	// SignatureOnly replaces each function chunk with a lightweight
+	// "signature" chunk: the doc comment and the declaration rendered from
+	// the AST without its body.
+	SignatureOnly bool
+	// FieldChunks additionally emits one "field" chunk per struct field.
+	FieldChunks bool
+	// SkipDeprecatedPackages drops packages whose package comment is marked
+	// deprecated instead of tagging their chunks.
+	SkipDeprecatedPackages bool
+	// StableIDs derives chunk IDs from the import path and symbol
+	// (importPath#func.Name) rather than the file path. Metadata.Path still
+	// records the current file.
+	StableIDs bool
+	// MaxTokens splits declarations whose body exceeds this many tokens into
+	// consecutive parts. Zero disables splitting.
+	MaxTokens int
+	// ChunkOverlap is the number of body tokens consecutive parts share.
+	ChunkOverlap int
+	// ExportedOnly skips unexported declarations, methods on unexported
+	// types and unexported fields, roughly matching go doc. File-doc and
+	// package-doc chunks are always kept.
+	ExportedOnly bool
+	// SymbolFilter, when set, keeps only declarations with a name it matches.
+	// Functions and types are tested by bare name; methods also by their
+	// receiver-qualified name (Handler.ServeHTTP), fields by Type.Field and
+	// bare name, and grouped values by each declared name. File-doc and
+	// package-doc chunks are always kept.
+	SymbolFilter *regexp.Regexp
+	// OnlySymbols, when set, keeps only the declarations it names and drops
+	// file-doc and package-doc chunks. It applies after SymbolFilter.
+	OnlySymbols *Allowlist
+	// AlwaysEmitPackageDoc keeps the package-doc chunk of a package whose
+	// other chunks were all filtered out, even under OnlySymbols, so a
+	// package overview is never lost. Kinds still applies.
+	AlwaysEmitPackageDoc bool
+	// SkipFilePatterns are extra path.Match patterns, such as "*.gen.go" or
+	// "zz_generated.*", for file names to skip as generated code, on top of
+	// DefaultSkipFilePatterns; ReplaceSkipPatterns uses them instead.
+	SkipFilePatterns    []string
+	ReplaceSkipPatterns bool
+	// SkipGenerated also skips files whose header carries the standard
+	// "// Code generated ... DO NOT EDIT." comment, whatever their name.
+	SkipGenerated bool
+	// IncludeTests chunks _test.go files instead of skipping them.
+	IncludeTests bool
+	// TestPackages, when IncludeTests is set, keeps only test files of one
+	// kind, TestPackageInternal or TestPackageExternal. Empty keeps both.
+	TestPackages string
+	// RequireDoc skips function, type and value declarations without a doc
+	// comment, whatever DocPolicy says about including it. File-doc,
+	// package-doc and field chunks are unaffected.
+	RequireDoc bool
+	// ModuleOverrides replaces ExportedOnly, IncludeTests and RequireDoc
+	// for the packages of a module, keyed by PackageSource.ModulePath.
+	ModuleOverrides map[string]ModuleOverride
+	// Kinds, when non-empty, restricts the chunks built to these values of
+	// Metadata.Kind (see AllKinds); other declarations are skipped before
+	// their chunks are rendered.
+	Kinds map[string]bool
+	// Directives emits a "directive" chunk per file holding its //go:generate
+	// and other tool directives verbatim.
+	Directives bool
+	// TypeBundles emits a "type-bundle" chunk per exported type with
+	// exported methods: its declaration plus the signatures of its methods
+	// from every file of the package.
+	TypeBundles bool
+	// TypeBundleMaxBytes bounds a type-bundle chunk, collapsing the type
+	// body and then dropping method signatures to fit. Zero means 4096.
+	TypeBundleMaxBytes int
+	// CommandSummaries emits a "command" chunk for each package main that
+	// defines flags or subcommands, listing them as an overview of how to
+	// run the executable.
+	CommandSummaries bool
+	// NativeCodeNotes emits a "native-code" chunk for each package that uses
+	// cgo or holds assembly, C or C++ sources, listing those files, since
+	// only Go source is chunked.
+	NativeCodeNotes bool
+	// UsageExamples, when positive, emits a "usage" chunk for each exported
+	// project function called elsewhere in the project, holding up to this
+	// many call snippets. Build and Stream find callers across all sources.
+	UsageExamples int
+	// Transformers post-process every chunk, in order, after metadata such
+	// as topics and source URLs is filled; see Transformer.
+	Transformers []Transformer
+	// PathStyle selects how Metadata.Path is written: PathModuleRelative
+	// (the default), PathImportPath or PathRepoRelative.
+	PathStyle string
+	// Root is the project root PathRepoRelative paths are relative to.
+	Root string
+	// Sort selects the output order, SortPath (the default) or SortSource.
+	Sort string
+	// MaxFileBytes skips source files larger than this many bytes; zero
+	// disables the guard. OnLargeFile, when set, is called for each one.
+	MaxFileBytes int64
+	OnLargeFile  func(path string, size int64)
+	// OnFileError, when set, is called for each source file that cannot be
+	// read or parsed; the file is skipped and the build continues. When nil
+	// the first such error aborts the build.
+	OnFileError func(path string, err error)
+	// Coverage, when set, accumulates how many declarations were emitted or
+	// excluded and why.
+	Coverage *Coverage
+	// SourceURLs maps a source kind to a URL template used to fill
+	// Metadata.SourceURL, e.g.
+	// "https://github.com/org/repo/blob/{commit}/{path}#L{start}-L{end}".
+	// Also available: {module}, {version} and {importPath}.
+	SourceURLs map[SourceKind]string
+	// Commit substitutes {commit} in SourceURLs for project chunks. Stdlib
+	// chunks use the Go version and third-party chunks their module version.
+	Commit string
+	// MethodTypeContext prefixes each method chunk with a
+	// "// on type T: ..." line holding the first line of its receiver
+	// type's doc comment.
+	MethodTypeContext bool
+	// AttachExamples appends the code of each ExampleT_M function in the
+	// package's _test.go files to the chunk of method T.M.
+	AttachExamples bool
+	// Implements maps "importPath.TypeName" to the interfaces the type
+	// implements, for Metadata.Implements. It comes from type-checking the
+	// project, which this package does not do itself.
+	Implements map[string][]string
+	// SearchKeywords fills Metadata.SearchKeywords.
+	SearchKeywords bool
+	// Topics tags chunks with a topic by symbol name; the first rule that
+	// matches wins.
+	Topics []TopicRule
+	// MaxTOCBytes shards a package-doc symbol index that would make the
+	// chunk larger than this into "package-toc" parts. Zero disables it.
+	MaxTOCBytes int
+}
+
+// Test package kinds recorded in Metadata.TestPackageKind.
+const (
+	// TestPackageInternal tests share the package under test and can reach
+	// its unexported API.
+	TestPackageInternal = "internal"
+	// TestPackageExternal tests live in package foo_test and exercise only
+	// the exported API, like a caller would.
+	TestPackageExternal = "external"
+)
+
+// testPackageKind reads just the package clause of a _test.go file.
+func testPackageKind(path string) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	return testKindOf(file.Name.Name), nil
+}
+
+func testKindOf(pkgName string) string {
+	if strings.HasSuffix(pkgName, "_test") {
+		return TestPackageExternal
+	}
+	return TestPackageInternal
+}
+
+// Output orders accepted by Options.Sort.
+const (
+	// SortPath orders chunks by module, file path, then chunk ID.
+	SortPath = "path"
+	// SortSource orders chunks by module and file path but keeps
+	// declarations in the order they appear in each file.
+	SortSource = "source"
+)
+
+// matchSymbol reports whether any of names passes SymbolFilter.
+func (o Options) matchSymbol(names ...string) bool {
+	if o.SymbolFilter == nil {
+		return true
+	}
+	for _, name := range names {
+		if o.SymbolFilter.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeKind reports whether chunks of kind should be built at all.
+func (o Options) includeKind(kind string) bool {
+	return len(o.Kinds) == 0 || o.Kinds[kind]
+}
+
+func (o Options) includeDoc(kind string) bool {
+	include, ok := o.DocPolicy[kind]
+	return !ok || include
+}
+
+// Build walks the provided package sources and returns extracted chunks,
+// sorted by module, file and then ID (or source position with SortSource).
+func Build(sources []PackageSource, opts Options) ([]Chunk, error) {
 	var all []Chunk
+	err := Stream(sources, opts, func(ch Chunk) error {
+		all = append(all, ch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Sort(all, opts)
+	return all, nil
+}
+
+// Stream chunks sources one package at a time and passes each chunk to fn
+// as soon as its package is built, so callers can encode output without
+// holding the whole build in memory. Chunks arrive in package order (the
+// order of sources) and, within a package, in file and declaration order
+// followed by the package doc; they are not globally sorted as Build's are.
+// Usage chunks, which need every package, come last. An error from fn stops
+// the build and is returned.
+func Stream(sources []PackageSource, opts Options, fn func(Chunk) error) error {
 	for _, src := range sources {
-		chunks, err := buildForPackage(src)
+		chunks, err := buildPackage(src, opts)
+		if err != nil {
+			return err
+		}
+		for _, ch := range chunks {
+			if err := fn(ch); err != nil {
+				return err
+			}
+		}
+	}
+	usage, err := buildUsage(sources, opts)
+	if err != nil {
+		return err
+	}
+	for _, ch := range usage {
+		if err := fn(ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildStream is Build for sources that arrive over time: each package is
+// chunked as soon as it is received, and the result is ordered exactly as
+// Build would order it once sources is closed. After the first error the
+// rest of sources is drained without being built, so senders never block.
+func BuildStream(sources <-chan PackageSource, opts Options) ([]Chunk, error) {
+	var all []Chunk
+	var received []PackageSource
+	var firstErr error
+	for src := range sources {
+		if firstErr != nil {
+			continue
+		}
+		received = append(received, src)
+		chunks, err := buildPackage(src, opts)
 		if err != nil {
-			return nil, err
+			firstErr = err
+			continue
 		}
 		all = append(all, chunks...)
 	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	usage, err := buildUsage(received, opts)
+	if err != nil {
+		return nil, err
+	}
+	all = append(all, usage...)
+	Sort(all, opts)
+	return all, nil
+}
+
+// buildPackage chunks one package with the options of its module, fills the
+// option-derived metadata, applies opts.PathStyle, runs the chunks through
+// opts.Transformers and hashes their final text.
+func buildPackage(src PackageSource, opts Options) ([]Chunk, error) {
+	opts = opts.forModule(src.ModulePath)
+	chunks, err := buildForPackage(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	enrich(chunks, opts)
+	applyPathStyle(chunks, src.ModuleDir, opts)
+	return hashContents(transform(chunks, opts.Transformers))
+}
+
+// buildUsage builds the usage chunks of sources and, like buildPackage,
+// enriches and transforms them.
+func buildUsage(sources []PackageSource, opts Options) ([]Chunk, error) {
+	chunks := buildUsageChunks(sources, opts)
+	enrich(chunks, opts)
+	moduleDirs := make(map[string]string, len(sources))
+	for _, src := range sources {
+		moduleDirs[src.ImportPath] = src.ModuleDir
+	}
+	for i := range chunks {
+		applyPathStyle(chunks[i:i+1], moduleDirs[chunks[i].Metadata.ImportPath], opts)
+	}
+	return hashContents(transform(chunks, opts.Transformers))
+}
+
+// enrich marks chunks of main packages and fills the metadata derived from
+// options: source URLs, topics and search keywords. Each chunk is handled on
+// its own, so it works on any subset of a build.
+func enrich(chunks []Chunk, opts Options) {
+	for i := range chunks {
+		chunks[i].Metadata.IsMain = chunks[i].Metadata.PackageName == "main"
+	}
+	applySourceURLs(chunks, opts)
+	applyTopics(chunks, opts)
+	applySearchKeywords(chunks, opts)
+}
 
-	sort.Slice(all, func(i, j int) bool {
+// Sort puts chunks in Build's output order: by module, then file, then
+// ID or source position depending on opts.Sort.
+func Sort(all []Chunk, opts Options) {
+	sort.SliceStable(all, func(i, j int) bool {
 		if all[i].Metadata.ModulePath != all[j].Metadata.ModulePath {
 			return all[i].Metadata.ModulePath < all[j].Metadata.ModulePath
 		}
 		if all[i].Metadata.Path != all[j].Metadata.Path {
 			return all[i].Metadata.Path < all[j].Metadata.Path
 		}
+		if opts.Sort == SortSource {
+			return false
+		}
 		return all[i].ID < all[j].ID
 	})
-	return all, nil
 }
 
-func buildForPackage(src PackageSource) ([]Chunk, error) {
+func buildForPackage(src PackageSource, opts Options) ([]Chunk, error) {
 	dirEntries, err := os.ReadDir(src.Dir)
 	if err != nil {
 		return nil, err
 	}
 
+	// Tally into a package-local Coverage so a package dropped as deprecated
+	// can be re-attributed before merging into the caller's totals.
+	total := opts.Coverage
+	if total != nil {
+		opts.Coverage = NewCoverage()
+		defer func() { total.merge(opts.Coverage) }()
+	}
+
 	var goFiles []string
 	for _, entry := range dirEntries {
 		if entry.IsDir() {
@@ -89,38 +472,104 @@ func buildForPackage(src PackageSource) ([]Chunk, error) {
 		if !strings.HasSuffix(name, ".go") {
 			continue
 		}
-		if shouldSkipFile(name) {
+		file := filepath.Join(src.Dir, name)
+		if strings.HasSuffix(name, "_test.go") && opts.IncludeTests {
+			if kind, err := testPackageKind(file); err == nil && opts.TestPackages != "" && kind != opts.TestPackages {
+				opts.Coverage.countSkippedFile(file, ExcludedTest)
+				continue
+			}
+		} else if opts.skipFile(file, name) {
+			reason := ExcludedGenerated
+			if strings.HasSuffix(name, "_test.go") {
+				reason = ExcludedTest
+			}
+			opts.Coverage.countSkippedFile(file, reason)
 			continue
 		}
-		goFiles = append(goFiles, filepath.Join(src.Dir, name))
+		if opts.MaxFileBytes > 0 {
+			if info, err := entry.Info(); err == nil && info.Size() > opts.MaxFileBytes {
+				opts.Coverage.countSkippedFile(file, ExcludedTooLarge)
+				if opts.OnLargeFile != nil {
+					opts.OnLargeFile(file, info.Size())
+				}
+				continue
+			}
+		}
+		goFiles = append(goFiles, file)
 	}
 	sort.Strings(goFiles)
 
-	var chunks []Chunk
+	var files []*fileContext
+	var parsed []*ast.File
 	for _, file := range goFiles {
-		fileChunks, err := parseFile(src, file)
+		fc, err := parseFile(src, file, opts)
 		if err != nil {
+			if opts.OnFileError != nil {
+				opts.OnFileError(file, err)
+				if opts.Coverage != nil {
+					opts.Coverage.FilesWithErrors++
+				}
+				continue
+			}
 			return nil, fmt.Errorf("chunk %s: %w", file, err)
 		}
-		chunks = append(chunks, fileChunks...)
+		files = append(files, fc)
+		if fc.testKind == "" {
+			parsed = append(parsed, fc.file)
+		}
 	}
-	return chunks, nil
-}
 
-func shouldSkipFile(name string) bool {
-	switch {
-	case strings.HasSuffix(name, "_test.go"),
-		strings.HasSuffix(name, "_mock.go"),
-		strings.HasSuffix(name, "_generated.go"),
-		strings.Contains(name, ".pb.go"),
-		strings.Contains(name, "_pb2.go"):
-		return true
-	default:
-		return false
+	// Methods may be declared in a different file from their receiver type,
+	// so type docs are gathered across the whole package before building.
+	var typeDocs map[string]string
+	if opts.MethodTypeContext {
+		typeDocs = collectTypeDocs(parsed)
+	}
+	var examples map[string][]string
+	if opts.AttachExamples {
+		examples = collectMethodExamples(src.Dir, opts)
+	}
+	var chunks []Chunk
+	for _, fc := range files {
+		fc.typeDocs = typeDocs
+		fc.examples = examples
+		chunks = append(chunks, buildFile(fc)...)
+	}
+	if opts.CommandSummaries && opts.OnlySymbols == nil && opts.includeKind("command") {
+		chunks = append(chunks, buildCommandSummary(src, files)...)
+	}
+	if opts.NativeCodeNotes && opts.OnlySymbols == nil && opts.includeKind("native-code") {
+		chunks = append(chunks, buildNativeNote(src, files)...)
 	}
+	if opts.TypeBundles && opts.includeKind("type-bundle") {
+		chunks = append(chunks, buildTypeBundles(files, opts)...)
+	}
+	// OnlySymbols drops package docs, unless the package would otherwise
+	// vanish from the output.
+	if opts.includeKind("package-doc") && (opts.OnlySymbols == nil || (opts.AlwaysEmitPackageDoc && len(chunks) == 0)) {
+		chunks = append(chunks, buildPackageDoc(src, parsed, opts)...)
+	}
+	if opts.StableIDs {
+		uniqueIDs(chunks)
+	}
+	if packageDeprecated(parsed) {
+		if opts.SkipDeprecatedPackages {
+			if c := opts.Coverage; c != nil {
+				c.exclude(ExcludedDeprecated, c.Emitted)
+				c.Total -= c.Emitted
+				c.Emitted = 0
+			}
+			return nil, nil
+		}
+		for i := range chunks {
+			chunks[i].Metadata.PackageDeprecated = true
+		}
+	}
+	return chunks, nil
 }
 
-func parseFile(src PackageSource, filePath string) ([]Chunk, error) {
+// parseFile reads and parses filePath into the context its chunks are built from.
+func parseFile(src PackageSource, filePath string, opts Options) (*fileContext, error) {
 	fset := token.NewFileSet()
 	content, err := os.ReadFile(filePath)
 	if err != nil {
@@ -132,14 +581,34 @@ func parseFile(src PackageSource, filePath string) ([]Chunk, error) {
 		return nil, err
 	}
 
-	fileRel := relativePath(src.ModuleDir, filePath)
+	var testKind string
+	if strings.HasSuffix(filePath, "_test.go") {
+		testKind = testKindOf(file.Name.Name)
+	}
+
+	return &fileContext{
+		src:         src,
+		path:        relativePath(src.ModuleDir, filePath),
+		pkg:         file.Name.Name,
+		fset:        fset,
+		content:     content,
+		file:        file,
+		opts:        opts,
+		symbolCount: countDecls(file),
+		testKind:    testKind,
+	}, nil
+}
+
+// buildFile emits the file-doc, directive and declaration chunks of a parsed file.
+func buildFile(fc *fileContext) []Chunk {
+	src, fileRel, fset, file, opts := fc.src, fc.path, fc.fset, fc.file, fc.opts
 	var chunks []Chunk
 
-	if doc := commentText(file.Doc); doc != "" {
+	if doc := fileDoc(file); doc != "" && opts.OnlySymbols == nil && opts.includeKind("file-doc") {
 		text := doc
 		if strings.TrimSpace(text) != "" {
 			chunks = append(chunks, Chunk{
-				ID:   fmt.Sprintf("%s:%s:file-doc", fileRel, file.Name.Name),
+				ID:   fc.id(fmt.Sprintf("%s:%s:file-doc", fileRel, file.Name.Name), "file-doc", filepath.Base(fset.Position(file.Package).Filename)),
 				Text: strings.TrimSpace(text),
 				Metadata: Metadata{
 					Path:          fileRel,
@@ -149,129 +618,354 @@ func parseFile(src PackageSource, filePath string) ([]Chunk, error) {
 					ModuleVersion: src.ModuleVersion,
 					Kind:          "file-doc",
 					Source:        string(src.Kind),
+					StartLine:     fset.Position(file.Doc.Pos()).Line,
+					EndLine:       fset.Position(file.Doc.End()).Line,
 				},
 			})
 		}
 	}
 
+	if opts.Directives && opts.OnlySymbols == nil && opts.includeKind("directive") {
+		chunks = append(chunks, buildDirectiveChunk(fc)...)
+	}
+
 	for _, decl := range file.Decls {
 		switch d := decl.(type) {
 		case *ast.FuncDecl:
-			chunks = append(chunks, buildFuncChunk(src, fileRel, file.Name.Name, fset, content, d))
+			chunks = append(chunks, buildFuncChunk(fc, d)...)
 		case *ast.GenDecl:
-			chunks = append(chunks, buildGenChunks(src, fileRel, file.Name.Name, fset, content, d)...)
+			chunks = append(chunks, buildGenChunks(fc, d)...)
 		default:
 			continue
 		}
 	}
 
-	return chunks, nil
+	for i := range chunks {
+		chunks[i].Metadata.FileSymbolCount = fc.symbolCount
+		chunks[i].Metadata.TestPackageKind = fc.testKind
+	}
+	return chunks
+}
+
+// fileContext carries the per-file state shared by the declaration builders.
+type fileContext struct {
+	src     PackageSource
+	path    string
+	pkg     string
+	fset    *token.FileSet
+	content []byte
+	file    *ast.File
+	opts    Options
+	// symbolCount is the file's declaration count, see Metadata.FileSymbolCount.
+	symbolCount int
+	// testKind is the Metadata.TestPackageKind of a _test.go file.
+	testKind string
+	// typeDocs maps the package's type names to the first line of their
+	// doc, when Options.MethodTypeContext is set.
+	typeDocs map[string]string
+	// examples maps "T.M" to the package's ExampleT_M functions, when
+	// Options.AttachExamples is set.
+	examples map[string][]string
+}
+
+// line returns the 1-based line of pos, as an editor shows it.
+func (fc *fileContext) line(pos token.Pos) int {
+	return fc.fset.Position(pos).Line
+}
+
+// id returns legacy unless StableIDs is set, in which case the ID is derived
+// from the import path and symbol so it survives moving code between files.
+func (fc *fileContext) id(legacy, kind, name string) string {
+	if !fc.opts.StableIDs {
+		return legacy
+	}
+	return stableID(fc.src.ImportPath, kind, name)
+}
+
+// stableID formats a file-independent chunk ID such as
+// "example.com/pkg#func.Server.Run".
+func stableID(importPath, kind, name string) string {
+	if name == "" {
+		return importPath + "#" + kind
+	}
+	return importPath + "#" + kind + "." + name
+}
+
+// uniqueIDs suffixes repeated IDs with ~2, ~3, ... in chunk order, which is
+// deterministic because files are parsed in sorted order. Stable IDs collide
+// for symbols such as init functions or build-tagged variants of one name.
+func uniqueIDs(chunks []Chunk) {
+	seen := make(map[string]int, len(chunks))
+	for i := range chunks {
+		id := chunks[i].ID
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			chunks[i].ID = fmt.Sprintf("%s~%d", id, n)
+		}
+	}
 }
 
-func buildFuncChunk(src PackageSource, path, pkg string, fset *token.FileSet, content []byte, decl *ast.FuncDecl) Chunk {
+func buildFuncChunk(fc *fileContext, decl *ast.FuncDecl) []Chunk {
+	src, path, pkg, opts := fc.src, fc.path, fc.pkg, fc.opts
+	kind := "function"
+	if opts.SignatureOnly {
+		kind = "signature"
+	}
+	if !opts.includeKind(kind) {
+		opts.Coverage.exclude(ExcludedKind, 1)
+		return nil
+	}
+	if opts.ExportedOnly && !decl.Name.IsExported() {
+		opts.Coverage.exclude(ExcludedUnexported, 1)
+		return nil
+	}
 	symbol := decl.Name.Name
+	var recvType, recvKind string
 	if decl.Recv != nil {
 		recv := formatReceiver(decl.Recv.List)
 		symbol = fmt.Sprintf("func (%s) %s", recv, decl.Name.Name)
+		recvType, recvKind = receiverBase(decl.Recv.List)
+		if opts.ExportedOnly && !ast.IsExported(recvType) {
+			opts.Coverage.exclude(ExcludedUnexported, 1)
+			return nil
+		}
 	} else {
 		symbol = fmt.Sprintf("func %s", decl.Name.Name)
 	}
 
-	text := extractSnippet(fset, content, decl.Pos(), decl.End())
-	doc := commentText(decl.Doc)
+	start, end := decl.Pos(), decl.End()
+	var doc, text string
+	var node ast.Node = decl
+	if opts.SignatureOnly {
+		// The signature is rendered from the AST, so an inline doc comment
+		// cannot be kept verbatim and is re-joined above it instead.
+		if opts.includeDoc("function") {
+			doc = commentText(decl.Doc)
+		}
+		end, node = decl.Type.End(), decl.Type
+		text = funcSignature(decl)
+	} else {
+		if opts.includeDoc("function") {
+			if opts.InlineDoc && decl.Doc != nil {
+				start = decl.Doc.Pos()
+			} else {
+				doc = commentText(decl.Doc)
+			}
+		}
+		text = extractSnippet(fc.fset, fc.content, start, end)
+	}
 
 	var buf bytes.Buffer
+	if summary := fc.typeDocs[recvType]; summary != "" {
+		fmt.Fprintf(&buf, "// on type %s: %s\n", recvType, summary)
+	}
+	if opts.IncludeImports {
+		buf.WriteString(importPreamble(fc.file, node))
+	}
 	if doc != "" {
 		buf.WriteString(strings.TrimSpace(doc))
 		buf.WriteString("\n\n")
 	}
+	headLen := buf.Len()
 	buf.WriteString(text)
 
-	id := fmt.Sprintf("%s:%s", path, decl.Name.Name)
-	return Chunk{
-		ID:   id,
-		Text: buf.String(),
-		Metadata: Metadata{
-			Path:          path,
-			PackageName:   pkg,
-			ImportPath:    src.ImportPath,
-			ModulePath:    src.ModulePath,
-			ModuleVersion: src.ModuleVersion,
-			Symbol:        symbol,
-			Kind:          "function",
-			Source:        string(src.Kind),
-		},
+	name := decl.Name.Name
+	if recvType != "" {
+		name = recvType + "." + name
+	}
+	if !opts.SignatureOnly {
+		for _, example := range fc.examples[name] {
+			buf.WriteString("\n\n// Example:\n")
+			buf.WriteString(example)
+		}
 	}
+	if !opts.matchSymbol(decl.Name.Name, name) {
+		opts.Coverage.exclude(ExcludedFiltered, 1)
+		return nil
+	}
+	if !opts.OnlySymbols.allow(name) {
+		opts.Coverage.exclude(ExcludedNotListed, 1)
+		return nil
+	}
+	if opts.RequireDoc && decl.Doc == nil {
+		opts.Coverage.exclude(ExcludedUndocumented, 1)
+		return nil
+	}
+	opts.Coverage.emitted(1)
+	id := fc.id(fmt.Sprintf("%s:%s", path, decl.Name.Name), "func", name)
+	if opts.SignatureOnly {
+		id = fc.id(fmt.Sprintf("%s:%s:signature", path, decl.Name.Name), "signature", name)
+	}
+	md := Metadata{
+		Path:          path,
+		PackageName:   pkg,
+		ImportPath:    src.ImportPath,
+		ModulePath:    src.ModulePath,
+		ModuleVersion: src.ModuleVersion,
+		Symbol:        symbol,
+		Signature:     funcSignature(decl),
+		Kind:          kind,
+		Source:        string(src.Kind),
+		StartLine:     fc.line(start),
+		EndLine:       fc.line(end),
+		ReceiverType:  recvType,
+		ReceiverKind:  recvKind,
+	}
+	setDeprecation(&md, decl.Doc)
+	return fc.split(Chunk{
+		ID:         id,
+		Text:       buf.String(),
+		Metadata:   md,
+		documented: decl.Doc != nil,
+	}, headLen)
 }
 
-func buildGenChunks(src PackageSource, path, pkg string, fset *token.FileSet, content []byte, decl *ast.GenDecl) []Chunk {
+func buildGenChunks(fc *fileContext, decl *ast.GenDecl) []Chunk {
+	src, path, pkg, opts := fc.src, fc.path, fc.pkg, fc.opts
 	if len(decl.Specs) == 0 {
 		return nil
 	}
 
 	var chunks []Chunk
-	for _, spec := range decl.Specs {
+	values := constValues(decl)
+	for i, spec := range decl.Specs {
 		switch s := spec.(type) {
 		case *ast.TypeSpec:
-			snippet := extractSnippet(fset, content, s.Pos(), s.End())
-			doc := gatherDoc(decl.Doc, s.Doc)
+			if opts.ExportedOnly && !s.Name.IsExported() {
+				opts.Coverage.exclude(ExcludedUnexported, 1)
+				continue
+			}
+			reason := ""
+			switch {
+			case !opts.includeKind("type"):
+				reason = ExcludedKind
+			case !opts.matchSymbol(s.Name.Name):
+				reason = ExcludedFiltered
+			case !opts.OnlySymbols.allow(s.Name.Name):
+				reason = ExcludedNotListed
+			case opts.RequireDoc && decl.Doc == nil && s.Doc == nil:
+				reason = ExcludedUndocumented
+			}
+			if reason != "" {
+				opts.Coverage.exclude(reason, 1)
+				if opts.FieldChunks {
+					chunks = append(chunks, buildFieldChunks(fc, s)...)
+				}
+				continue
+			}
+			opts.Coverage.emitted(1)
+			start := s.Pos()
+			var doc string
+			if opts.includeDoc("type") {
+				doc, start = specDoc(decl, s.Doc, start, opts.InlineDoc)
+			}
+			snippet := extractSnippet(fc.fset, fc.content, start, s.End())
 			var buf bytes.Buffer
+			if opts.IncludeImports {
+				buf.WriteString(importPreamble(fc.file, s))
+			}
 			if doc != "" {
 				buf.WriteString(doc)
 				buf.WriteString("\n\n")
 			}
+			headLen := buf.Len()
 			buf.WriteString(snippet)
 
-			id := fmt.Sprintf("%s:type:%s", path, s.Name.Name)
-			chunks = append(chunks, Chunk{
-				ID:   id,
-				Text: buf.String(),
-				Metadata: Metadata{
-					Path:          path,
-					PackageName:   pkg,
-					ImportPath:    src.ImportPath,
-					ModulePath:    src.ModulePath,
-					ModuleVersion: src.ModuleVersion,
-					Symbol:        fmt.Sprintf("type %s", s.Name.Name),
-					Kind:          "type",
-					Source:        string(src.Kind),
-				},
-			})
+			id := fc.id(fmt.Sprintf("%s:type:%s", path, s.Name.Name), "type", s.Name.Name)
+			md := Metadata{
+				Path:          path,
+				PackageName:   pkg,
+				ImportPath:    src.ImportPath,
+				ModulePath:    src.ModulePath,
+				ModuleVersion: src.ModuleVersion,
+				Symbol:        fmt.Sprintf("type %s", s.Name.Name),
+				Kind:          "type",
+				Source:        string(src.Kind),
+				StartLine:     fc.line(start),
+				EndLine:       fc.line(s.End()),
+				Implements:    opts.Implements[src.ImportPath+"."+s.Name.Name],
+			}
+			setDeprecation(&md, decl.Doc, s.Doc)
+			chunks = append(chunks, fc.split(Chunk{
+				ID:         id,
+				Text:       buf.String(),
+				Metadata:   md,
+				documented: decl.Doc != nil || s.Doc != nil,
+			}, headLen)...)
+			if opts.FieldChunks {
+				chunks = append(chunks, buildFieldChunks(fc, s)...)
+			}
 		case *ast.ValueSpec:
 			// group value specs to reduce noise.
 			if len(s.Names) == 0 {
 				continue
 			}
-			snippet := extractSnippet(fset, content, s.Pos(), s.End())
-			doc := gatherDoc(decl.Doc, s.Doc)
+			if !opts.includeKind(strings.ToLower(decl.Tok.String())) {
+				opts.Coverage.exclude(ExcludedKind, 1)
+				continue
+			}
+			if opts.ExportedOnly && !anyExported(s.Names) {
+				opts.Coverage.exclude(ExcludedUnexported, 1)
+				continue
+			}
+			if !opts.matchSymbol(identNames(s.Names)...) {
+				opts.Coverage.exclude(ExcludedFiltered, 1)
+				continue
+			}
+			if !opts.OnlySymbols.allow(identNames(s.Names)...) {
+				opts.Coverage.exclude(ExcludedNotListed, 1)
+				continue
+			}
+			if opts.RequireDoc && decl.Doc == nil && s.Doc == nil {
+				opts.Coverage.exclude(ExcludedUndocumented, 1)
+				continue
+			}
+			opts.Coverage.emitted(1)
+			start := s.Pos()
+			var doc string
+			if opts.includeDoc(strings.ToLower(decl.Tok.String())) {
+				doc, start = specDoc(decl, s.Doc, start, opts.InlineDoc)
+			}
+			snippet := extractSnippet(fc.fset, fc.content, start, s.End())
 			var buf bytes.Buffer
 			if doc != "" {
 				buf.WriteString(doc)
 				buf.WriteString("\n\n")
 			}
+			headLen := buf.Len()
 			buf.WriteString(snippet)
-
-			nameParts := make([]string, len(s.Names))
-			for i, name := range s.Names {
-				nameParts[i] = name.Name
+			var extra map[string]string
+			if values != nil && values[i] != nil {
+				buf.WriteString(constComment(values[i]))
+				if len(values[i]) == 1 {
+					extra = map[string]string{"value": values[i][0]}
+				}
 			}
+
+			nameParts := identNames(s.Names)
 			symbol := fmt.Sprintf("%s %s", strings.ToLower(decl.Tok.String()), strings.Join(nameParts, ", "))
-			id := fmt.Sprintf("%s:%s:%s", path, strings.ToLower(decl.Tok.String()), strings.Join(nameParts, ","))
+			id := fc.id(fmt.Sprintf("%s:%s:%s", path, strings.ToLower(decl.Tok.String()), strings.Join(nameParts, ",")), strings.ToLower(decl.Tok.String()), strings.Join(nameParts, ","))
 
-			chunks = append(chunks, Chunk{
-				ID:   id,
-				Text: buf.String(),
-				Metadata: Metadata{
-					Path:          path,
-					PackageName:   pkg,
-					ImportPath:    src.ImportPath,
-					ModulePath:    src.ModulePath,
-					ModuleVersion: src.ModuleVersion,
-					Symbol:        symbol,
-					Kind:          strings.ToLower(decl.Tok.String()),
-					Source:        string(src.Kind),
-				},
-			})
+			md := Metadata{
+				Path:          path,
+				PackageName:   pkg,
+				ImportPath:    src.ImportPath,
+				ModulePath:    src.ModulePath,
+				ModuleVersion: src.ModuleVersion,
+				Symbol:        symbol,
+				Kind:          strings.ToLower(decl.Tok.String()),
+				Source:        string(src.Kind),
+				StartLine:     fc.line(start),
+				EndLine:       fc.line(s.End()),
+				Extra:         extra,
+			}
+			setDeprecation(&md, decl.Doc, s.Doc)
+			chunks = append(chunks, fc.split(Chunk{
+				ID:         id,
+				Text:       buf.String(),
+				Metadata:   md,
+				documented: decl.Doc != nil || s.Doc != nil,
+			}, headLen)...)
 		default:
 			continue
 		}
@@ -279,6 +973,107 @@ func buildGenChunks(src PackageSource, path, pkg string, fset *token.FileSet, co
 	return chunks
 }
 
+func identNames(idents []*ast.Ident) []string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return names
+}
+
+func anyExported(names []*ast.Ident) bool {
+	for _, name := range names {
+		if name.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// specDoc returns the doc text to prepend to a spec snippet and the position the
+// snippet should start at. When inline is set, the comment directly above the
+// spec stays in the snippet and only a separate group doc is returned as text.
+func specDoc(decl *ast.GenDecl, doc *ast.CommentGroup, pos token.Pos, inline bool) (string, token.Pos) {
+	if !inline {
+		return gatherDoc(decl.Doc, doc), pos
+	}
+	if !decl.Lparen.IsValid() {
+		// Ungrouped declarations attach the doc to the GenDecl itself.
+		if decl.Doc != nil {
+			return "", decl.Doc.Pos()
+		}
+		return "", pos
+	}
+	if doc != nil {
+		pos = doc.Pos()
+	}
+	return commentText(decl.Doc), pos
+}
+
+// importPreamble renders a "// imports:" line listing the file imports that
+// node refers to through package-qualified selectors.
+func importPreamble(file *ast.File, node ast.Node) string {
+	byName := make(map[string]*ast.ImportSpec, len(file.Imports))
+	for _, spec := range file.Imports {
+		if name := importName(spec); name != "_" && name != "." {
+			byName[name] = spec
+		}
+	}
+	if len(byName) == 0 {
+		return ""
+	}
+
+	used := make(map[string]struct{})
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		// Package qualifiers are never resolved to a local object by the parser.
+		if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+			if _, known := byName[ident.Name]; known {
+				used[ident.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+	if len(used) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %s", name, byName[name].Path.Value)
+	}
+	return "// imports: " + strings.Join(parts, ", ") + "\n"
+}
+
+// importName returns the identifier an import is referred to by, guessing the
+// package name from the path when the import is not explicitly named.
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	path := strings.Trim(spec.Path.Value, `"`)
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	// Major version suffixes (example.com/mod/v2) are not part of the name.
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	// gopkg.in style versions (yaml.v3) are not part of the name either.
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 func extractSnippet(fset *token.FileSet, content []byte, start, end token.Pos) string {
 	startPos := fset.PositionFor(start, true).Offset
 	endPos := fset.PositionFor(end, true).Offset
@@ -291,11 +1086,36 @@ func extractSnippet(fset *token.FileSet, content []byte, start, end token.Pos) s
 	return strings.TrimSpace(string(content[startPos:endPos]))
 }
 
+// collectTypeDocs maps each type declared in files to the first line of its
+// doc comment, or of the enclosing type group's doc when the spec has none.
+func collectTypeDocs(files []*ast.File) map[string]string {
+	docs := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := commentText(ts.Doc)
+				if doc == "" && len(gen.Specs) == 1 {
+					doc = commentText(gen.Doc)
+				}
+				if first, _, _ := strings.Cut(doc, "\n"); first != "" {
+					docs[ts.Name.Name] = first
+				}
+			}
+		}
+	}
+	return docs
+}
+
 func commentText(g *ast.CommentGroup) string {
 	if g == nil {
 		return ""
 	}
-	return strings.TrimSpace(g.Text())
+	return NormalizeDoc(strings.TrimSpace(g.Text()))
 }
 
 func gatherDoc(groups ...*ast.CommentGroup) string {
@@ -309,6 +1129,18 @@ func gatherDoc(groups ...*ast.CommentGroup) string {
 	return strings.TrimSpace(strings.Join(parts, "\n"))
 }
 
+// funcSignature renders the declaration line of a function without its doc or body.
+func funcSignature(decl *ast.FuncDecl) string {
+	sig := *decl
+	sig.Doc = nil
+	sig.Body = nil
+	var buf bytes.Buffer
+	if err := formatNode(&buf, &sig); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
 func formatReceiver(list []*ast.Field) string {
 	if len(list) == 0 {
 		return ""
@@ -324,6 +1156,36 @@ func formatReceiver(list []*ast.Field) string {
 	return strings.Join(parts, ", ")
 }
 
+// receiverBase returns the base type name of a method receiver and whether it
+// is a pointer or value receiver.
+func receiverBase(list []*ast.Field) (string, string) {
+	if len(list) == 0 {
+		return "", ""
+	}
+	kind := "value"
+	expr := list[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.ParenExpr:
+			expr = t.X
+			continue
+		case *ast.StarExpr:
+			kind = "pointer"
+			expr = t.X
+			continue
+		case *ast.IndexExpr:
+			expr = t.X
+			continue
+		case *ast.IndexListExpr:
+			expr = t.X
+			continue
+		case *ast.Ident:
+			return t.Name, kind
+		}
+		return exprString(expr), kind
+	}
+}
+
 func exprString(expr ast.Expr) string {
 	var buf bytes.Buffer
 	if err := formatNode(&buf, expr); err != nil {
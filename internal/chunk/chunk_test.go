@@ -0,0 +1,245 @@
+package chunk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildFiles writes files, keyed by name, into a new package directory and
+// chunks it as the project package example.com/m.
+func buildFiles(t *testing.T, files map[string]string, opts Options) []Chunk {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks, err := Build([]PackageSource{{
+		ModulePath: "example.com/m",
+		ModuleDir:  dir,
+		ImportPath: "example.com/m",
+		Dir:        dir,
+		Kind:       SourceProject,
+	}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+// chunkByID returns the chunk with id, failing the test when there is none.
+func chunkByID(t *testing.T, chunks []Chunk, id string) Chunk {
+	t.Helper()
+	for _, ch := range chunks {
+		if ch.ID == id {
+			return ch
+		}
+	}
+	t.Fatalf("no chunk %s", id)
+	return Chunk{}
+}
+
+const docPolicySrc = `package m
+
+// Server serves requests.
+type Server struct{}
+
+// Run starts the server.
+func Run() {}
+`
+
+func TestDocPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          map[string]bool
+		wantTypeDoc     bool
+		wantFunctionDoc bool
+	}{
+		{name: "default keeps docs", wantTypeDoc: true, wantFunctionDoc: true},
+		{name: "strip functions", policy: map[string]bool{"function": false}, wantTypeDoc: true, wantFunctionDoc: false},
+		{name: "strip types", policy: map[string]bool{"type": false, "function": true}, wantTypeDoc: false, wantFunctionDoc: true},
+		{name: "strip both", policy: map[string]bool{"type": false, "function": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := buildFiles(t, map[string]string{"a.go": docPolicySrc}, Options{DocPolicy: tt.policy})
+			typ := chunkByID(t, chunks, "a.go:type:Server")
+			fn := chunkByID(t, chunks, "a.go:Run")
+			if got := strings.Contains(typ.Text, "Server serves requests."); got != tt.wantTypeDoc {
+				t.Errorf("type doc included = %v, want %v:\n%s", got, tt.wantTypeDoc, typ.Text)
+			}
+			if got := strings.Contains(fn.Text, "Run starts the server."); got != tt.wantFunctionDoc {
+				t.Errorf("function doc included = %v, want %v:\n%s", got, tt.wantFunctionDoc, fn.Text)
+			}
+		})
+	}
+}
+
+const sortSrc = `package m
+
+func Zeta() {}
+
+func Alpha() {}
+
+func Mid() {}
+`
+
+func TestSortOrder(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{sort: SortPath, want: []string{"a.go:Alpha", "a.go:Mid", "a.go:Zeta", "b.go:Beta"}},
+		{sort: SortSource, want: []string{"a.go:Zeta", "a.go:Alpha", "a.go:Mid", "b.go:Beta"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			chunks := buildFiles(t, map[string]string{
+				"a.go": sortSrc,
+				"b.go": "package m\n\nfunc Beta() {}\n",
+			}, Options{Sort: tt.sort})
+			var got []string
+			for _, ch := range chunks {
+				if ch.Metadata.Kind == "function" {
+					got = append(got, ch.ID)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSymbolCount(t *testing.T) {
+	chunks := buildFiles(t, map[string]string{
+		"a.go": allowlistSrc,
+		"b.go": "// Package m is small.\npackage m\n\n// Beta is alone in its file.\nfunc Beta() {}\n",
+	}, Options{})
+	want := map[string]int{"a.go": 7, "b.go": 1}
+	seen := make(map[string]int)
+	for _, ch := range chunks {
+		if ch.Metadata.Kind == "package-doc" {
+			if ch.Metadata.FileSymbolCount != 0 {
+				t.Errorf("%s: FileSymbolCount = %d, want 0", ch.ID, ch.Metadata.FileSymbolCount)
+			}
+			continue
+		}
+		file := ch.Metadata.Path
+		seen[file]++
+		if ch.Metadata.FileSymbolCount != want[file] {
+			t.Errorf("%s: FileSymbolCount = %d, want %d", ch.ID, ch.Metadata.FileSymbolCount, want[file])
+		}
+	}
+	for file := range want {
+		if seen[file] == 0 {
+			t.Errorf("no chunks from %s", file)
+		}
+	}
+}
+
+// writePackages writes one single-file package per name under a new module
+// directory and returns their sources, in order.
+func writePackages(t *testing.T, names ...string) []PackageSource {
+	t.Helper()
+	root := t.TempDir()
+	var sources []PackageSource
+	for _, name := range names {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		src := "// Package " + name + " is for tests.\npackage " + name + "\n\n// Run runs.\nfunc Run() {}\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, PackageSource{
+			ModulePath: "example.com/m",
+			ModuleDir:  root,
+			ImportPath: "example.com/m/" + name,
+			Dir:        dir,
+			Kind:       SourceProject,
+		})
+	}
+	return sources
+}
+
+func TestBuildStreamChunksBeforeClose(t *testing.T) {
+	sources := writePackages(t, "zeta", "alpha")
+	built := make(chan string, 16)
+	opts := Options{Transformers: []Transformer{TransformerFunc(func(ch Chunk) (Chunk, bool, error) {
+		built <- ch.Metadata.ImportPath
+		return ch, true, nil
+	})}}
+
+	in := make(chan PackageSource)
+	type result struct {
+		chunks []Chunk
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		chunks, err := BuildStream(in, opts)
+		done <- result{chunks, err}
+	}()
+
+	// Each package must be chunked while the channel is still open, before
+	// the next one is sent.
+	for _, src := range sources {
+		in <- src
+		select {
+		case got := <-built:
+			if got != src.ImportPath {
+				t.Fatalf("built %s, want %s", got, src.ImportPath)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s was not chunked before the stream closed", src.ImportPath)
+		}
+		for len(built) > 0 {
+			<-built
+		}
+	}
+	close(in)
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	want, err := Build(sources, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(res.chunks), len(want))
+	}
+	for i := range want {
+		if res.chunks[i].ID != want[i].ID || res.chunks[i].Metadata.ImportPath != want[i].Metadata.ImportPath {
+			t.Errorf("chunk %d = %s (%s), want %s (%s) in Build order", i, res.chunks[i].ID, res.chunks[i].Metadata.ImportPath, want[i].ID, want[i].Metadata.ImportPath)
+		}
+	}
+}
+
+func TestBuildStreamDrainsAfterError(t *testing.T) {
+	sources := writePackages(t, "a", "b")
+	bad := sources[0]
+	bad.Dir = filepath.Join(bad.ModuleDir, "missing")
+
+	in := make(chan PackageSource)
+	go func() {
+		defer close(in)
+		for _, src := range []PackageSource{bad, sources[0], sources[1]} {
+			in <- src
+		}
+	}()
+	if _, err := BuildStream(in, Options{}); err == nil {
+		t.Fatal("BuildStream succeeded with a missing package directory")
+	}
+}
@@ -0,0 +1,213 @@
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// flagFuncs maps the flag and flag.FlagSet functions that define a flag to
+// the positions of their name and usage arguments; the default value, if
+// any, sits between them.
+var flagFuncs = map[string][2]int{
+	"Bool": {0, 2}, "Duration": {0, 2}, "Float64": {0, 2}, "Int": {0, 2},
+	"Int64": {0, 2}, "String": {0, 2}, "Uint": {0, 2}, "Uint64": {0, 2},
+	"BoolVar": {1, 3}, "DurationVar": {1, 3}, "Float64Var": {1, 3}, "IntVar": {1, 3},
+	"Int64Var": {1, 3}, "StringVar": {1, 3}, "UintVar": {1, 3}, "Uint64Var": {1, 3},
+	"TextVar": {1, 3}, "Var": {1, 2}, "Func": {0, 1}, "BoolFunc": {0, 1},
+}
+
+// commandFlag is one flag definition found in a main package.
+type commandFlag struct {
+	set, name, def, usage string
+}
+
+// buildCommandSummary returns a "command" chunk for a main package naming
+// the executable and listing the subcommands and flags it defines, so "how
+// do I run this" has an answer. Flags are found from calls to the standard
+// flag package, grouped by the name given to flag.NewFlagSet; subcommands
+// are the string cases of a switch on os.Args[1] or flag.Arg(0), directly
+// or through a variable. Only literal names and usages are listed. It
+// returns nil when the package is not main or nothing was found.
+func buildCommandSummary(src PackageSource, files []*fileContext) []Chunk {
+	var flags []commandFlag
+	var subcommands []string
+	seenSub := make(map[string]bool)
+	for _, fc := range files {
+		if fc.testKind != "" || fc.pkg != "main" || !importsPath(fc.file, "flag") {
+			continue
+		}
+		setNames := make(map[*ast.Object]string)
+		argVars := make(map[*ast.Object]bool)
+		ast.Inspect(fc.file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if len(n.Lhs) != len(n.Rhs) {
+					return true
+				}
+				for i, rhs := range n.Rhs {
+					ident, ok := n.Lhs[i].(*ast.Ident)
+					if !ok || ident.Obj == nil {
+						continue
+					}
+					if isSubcommandArg(rhs) {
+						argVars[ident.Obj] = true
+					}
+					if call, ok := rhs.(*ast.CallExpr); ok && isSelector(call.Fun, "flag", "NewFlagSet") && len(call.Args) > 0 {
+						setNames[ident.Obj] = stringLit(call.Args[0])
+					}
+				}
+			case *ast.SwitchStmt:
+				tag := n.Tag
+				if ident, ok := tag.(*ast.Ident); ok && ident.Obj != nil && argVars[ident.Obj] || tag != nil && isSubcommandArg(tag) {
+					for _, stmt := range n.Body.List {
+						for _, expr := range stmt.(*ast.CaseClause).List {
+							if name := stringLit(expr); name != "" && !strings.HasPrefix(name, "-") && !seenSub[name] {
+								seenSub[name] = true
+								subcommands = append(subcommands, name)
+							}
+						}
+					}
+				}
+			case *ast.CallExpr:
+				sel, ok := n.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				pos, ok := flagFuncs[sel.Sel.Name]
+				recv, _ := sel.X.(*ast.Ident)
+				if !ok || recv == nil || len(n.Args) <= pos[1] {
+					return true
+				}
+				set := ""
+				if recv.Name != "flag" || recv.Obj != nil {
+					name, known := setNames[recv.Obj]
+					if !known {
+						return true
+					}
+					set = name
+				}
+				f := commandFlag{set: set, name: stringLit(n.Args[pos[0]]), usage: stringLit(n.Args[pos[1]])}
+				if pos[1]-pos[0] == 2 {
+					f.def = exprString(n.Args[pos[0]+1])
+				}
+				if f.name != "" {
+					flags = append(flags, f)
+				}
+			}
+			return true
+		})
+	}
+	if len(flags) == 0 && len(subcommands) == 0 {
+		return nil
+	}
+
+	name := path.Base(src.ImportPath)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "command %s // package main, import %q", name, src.ImportPath)
+	if len(subcommands) > 0 {
+		fmt.Fprintf(&buf, "\n\nSubcommands: %s", strings.Join(subcommands, ", "))
+	}
+	var sets []string
+	bySet := make(map[string][]commandFlag)
+	for _, f := range flags {
+		if _, ok := bySet[f.set]; !ok {
+			sets = append(sets, f.set)
+		}
+		bySet[f.set] = append(bySet[f.set], f)
+	}
+	for _, set := range sets {
+		if set == "" {
+			buf.WriteString("\n\nFlags:")
+		} else {
+			fmt.Fprintf(&buf, "\n\nFlags of %s %s:", name, set)
+		}
+		for _, f := range bySet[set] {
+			fmt.Fprintf(&buf, "\n  -%s", f.name)
+			if f.def != "" && f.def != `""` && f.def != "false" {
+				fmt.Fprintf(&buf, " (default %s)", f.def)
+			}
+			if f.usage != "" {
+				fmt.Fprintf(&buf, "  %s", f.usage)
+			}
+		}
+	}
+
+	dirRel := relativePath(src.ModuleDir, src.Dir)
+	id := fmt.Sprintf("%s:main:command", dirRel)
+	if files[0].opts.StableIDs {
+		id = stableID(src.ImportPath, "command", "")
+	}
+	return []Chunk{{
+		ID:   id,
+		Text: buf.String(),
+		Metadata: Metadata{
+			Path:          dirRel,
+			PackageName:   "main",
+			ImportPath:    src.ImportPath,
+			ModulePath:    src.ModulePath,
+			ModuleVersion: src.ModuleVersion,
+			Symbol:        name,
+			Kind:          "command",
+			Source:        string(src.Kind),
+			Extra: map[string]string{
+				"flags":       strconv.Itoa(len(flags)),
+				"subcommands": strconv.Itoa(len(subcommands)),
+			},
+		},
+	}}
+}
+
+// isSubcommandArg reports whether expr is os.Args[1] or flag.Arg(0), where
+// a subcommand name is usually read from.
+func isSubcommandArg(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		lit, ok := e.Index.(*ast.BasicLit)
+		return ok && lit.Value == "1" && isSelector(e.X, "os", "Args")
+	case *ast.CallExpr:
+		if len(e.Args) != 1 || !isSelector(e.Fun, "flag", "Arg") {
+			return false
+		}
+		lit, ok := e.Args[0].(*ast.BasicLit)
+		return ok && lit.Value == "0"
+	}
+	return false
+}
+
+// isSelector reports whether expr is pkg.name for an unshadowed pkg.
+func isSelector(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == pkg && ident.Obj == nil
+}
+
+// stringLit returns the value of a string literal, or "" for any other
+// expression.
+func stringLit(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// importsPath reports whether file imports importPath under its own name.
+func importsPath(file *ast.File, importPath string) bool {
+	for _, spec := range file.Imports {
+		if spec.Name == nil && strings.Trim(spec.Path.Value, `"`) == importPath {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// Exclusion reasons recorded in Coverage.Excluded.
+const (
+	ExcludedTest         = "test-file"
+	ExcludedGenerated    = "generated-file"
+	ExcludedTooLarge     = "too-large"
+	ExcludedUnexported   = "unexported"
+	ExcludedFiltered     = "symbol-filter"
+	ExcludedNotListed    = "only-symbols"
+	ExcludedUndocumented = "undocumented"
+	ExcludedDeprecated   = "deprecated-package"
+	ExcludedKind         = "kind"
+)
+
+// Coverage tallies declarations seen during a build against those that
+// produced chunks. A declaration is a function or method, a type spec, or a
+// const/var spec; Total always equals Emitted plus the sum of Excluded.
+type Coverage struct {
+	Total    int            `json:"total"`
+	Emitted  int            `json:"emitted"`
+	Excluded map[string]int `json:"excluded"`
+	// FilesWithErrors counts files skipped because they could not be parsed;
+	// their declarations are unknown and not part of Total.
+	FilesWithErrors int `json:"filesWithErrors"`
+}
+
+// NewCoverage returns an empty Coverage ready to pass in Options.
+func NewCoverage() *Coverage {
+	return &Coverage{Excluded: make(map[string]int)}
+}
+
+// emitted records n declarations that produced chunks. Safe on a nil receiver.
+func (c *Coverage) emitted(n int) {
+	if c == nil {
+		return
+	}
+	c.Total += n
+	c.Emitted += n
+}
+
+// exclude records n declarations dropped for reason. Safe on a nil receiver.
+func (c *Coverage) exclude(reason string, n int) {
+	if c == nil || n == 0 {
+		return
+	}
+	c.Total += n
+	c.Excluded[reason] += n
+}
+
+// ExcludedFor returns how many declarations were dropped for reason. Safe on
+// a nil receiver.
+func (c *Coverage) ExcludedFor(reason string) int {
+	if c == nil {
+		return 0
+	}
+	return c.Excluded[reason]
+}
+
+func (c *Coverage) merge(other *Coverage) {
+	if c == nil || other == nil {
+		return
+	}
+	c.Total += other.Total
+	c.Emitted += other.Emitted
+	c.FilesWithErrors += other.FilesWithErrors
+	for reason, n := range other.Excluded {
+		c.Excluded[reason] += n
+	}
+}
+
+// countSkippedFile parses a file left out of the build and records its
+// declarations under reason. Unparsable files are ignored.
+func (c *Coverage) countSkippedFile(path, reason string) {
+	if c == nil {
+		return
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return
+	}
+	c.exclude(reason, countDecls(file))
+}
+
+// countDecls returns the number of declarations in file as Coverage counts them.
+func countDecls(file *ast.File) int {
+	n := 0
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			n++
+		case *ast.GenDecl:
+			if d.Tok != token.IMPORT {
+				n += len(d.Specs)
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package chunk
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCoverageSkippedFileReasons(t *testing.T) {
+	big := "package m\n\n// Big is large.\nfunc Big() {}\n\nfunc Bigger() {}\n\n// " + strings.Repeat("x", 200) + "\n"
+	files := map[string]string{
+		"a.go":        "package m\n\n// Run runs.\nfunc Run() {}\n",
+		"big.go":      big,
+		"api.pb.go":   "package m\n\nfunc Generated() {}\n",
+		"a_test.go":   "package m\n\nfunc helperForTests() {}\n",
+		"ext_test.go": "package m_test\n\nfunc External() {}\n",
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]int
+	}{
+		{
+			name: "oversized file",
+			opts: Options{MaxFileBytes: 150},
+			want: map[string]int{ExcludedTooLarge: 2, ExcludedGenerated: 1, ExcludedTest: 2},
+		},
+		{
+			name: "no size guard",
+			want: map[string]int{ExcludedGenerated: 1, ExcludedTest: 2},
+		},
+		{
+			name: "test package filter",
+			opts: Options{IncludeTests: true, TestPackages: TestPackageExternal},
+			want: map[string]int{ExcludedGenerated: 1, ExcludedTest: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverage := NewCoverage()
+			opts := tt.opts
+			opts.Coverage = coverage
+			buildFiles(t, files, opts)
+			for _, reason := range []string{ExcludedTooLarge, ExcludedGenerated, ExcludedTest} {
+				if got := coverage.ExcludedFor(reason); got != tt.want[reason] {
+					t.Errorf("ExcludedFor(%s) = %d, want %d", reason, got, tt.want[reason])
+				}
+			}
+		})
+	}
+}
+
+const coverageSrc = `package m
+
+import "fmt"
+
+// Server serves.
+type Server struct{}
+
+// Start starts.
+func (s *Server) Start() {}
+
+func (s *Server) stop() {}
+
+// Run runs.
+func Run() { fmt.Println() }
+
+func Undocumented() {}
+
+func helper() {}
+
+type internalState struct{}
+
+const (
+	// A is a.
+	A = 1
+	b = 2
+)
+
+var Default = Server{}
+`
+
+func TestCoverageReconciles(t *testing.T) {
+	files := map[string]string{
+		"a.go":        coverageSrc,
+		"gen_mock.go": "package m\n\nfunc Mock() {}\n\ntype MockStore struct{}\n",
+		"a_test.go":   "package m\n\nfunc TestRun() {}\n",
+		"x_test.go":   "package m_test\n\nfunc ExampleRun() {}\n",
+	}
+	// a.go has 10 declarations (the import does not count) and the other
+	// files 4 between them.
+	const total = 14
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "defaults"},
+		{name: "exported only", opts: Options{ExportedOnly: true}},
+		{name: "symbol filter and docs", opts: Options{SymbolFilter: regexp.MustCompile(`^(Run|Server|Start|A|b)$`), RequireDoc: true}},
+		{name: "kinds", opts: Options{Kinds: map[string]bool{"function": true}}},
+		{name: "only symbols", opts: Options{OnlySymbols: NewAllowlist([]string{"Run", "Server.Start"})}},
+		{name: "test package filter", opts: Options{IncludeTests: true, TestPackages: TestPackageInternal, ExportedOnly: true}},
+		{name: "oversized file", opts: Options{MaxFileBytes: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverage := NewCoverage()
+			opts := tt.opts
+			opts.Coverage = coverage
+			buildFiles(t, files, opts)
+
+			excluded := 0
+			for _, n := range coverage.Excluded {
+				excluded += n
+			}
+			if coverage.Total != coverage.Emitted+excluded {
+				t.Errorf("Total %d != Emitted %d + excluded %d (%v)", coverage.Total, coverage.Emitted, excluded, coverage.Excluded)
+			}
+			if coverage.Total != total {
+				t.Errorf("Total = %d, want %d (%v)", coverage.Total, total, coverage.Excluded)
+			}
+			if got := coverage.ExcludedFor(ExcludedGenerated); got != 2 {
+				t.Errorf("generated-file = %d, want 2", got)
+			}
+			if opts.MaxFileBytes > 0 && coverage.ExcludedFor(ExcludedTooLarge) != 10 {
+				t.Errorf("too-large = %d, want a.go's 10", coverage.ExcludedFor(ExcludedTooLarge))
+			}
+		})
+	}
+}
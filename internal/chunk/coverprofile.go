@@ -0,0 +1,127 @@
+package chunk
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CoverBlock is one statement block of a go test -coverprofile file.
+type CoverBlock struct {
+	StartLine, EndLine int
+	Stmts              int
+	Count              int
+}
+
+// CoverProfile holds coverage blocks keyed by file, named as the profile
+// names them: the package import path joined with the file's base name.
+type CoverProfile map[string][]CoverBlock
+
+// ParseCoverProfile reads a profile written by go test -coverprofile, in any
+// of its set, count or atomic modes.
+func ParseCoverProfile(file string) (CoverProfile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profile := make(CoverProfile)
+	// A block listed more than once (profiles merged across packages, or
+	// -coverpkg runs) is counted once with its highest count.
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		name, block, err := parseCoverLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, lineNo, err)
+		}
+		key := line[:strings.LastIndex(line, " ")]
+		if i, ok := seen[key]; ok {
+			profile[name][i].Count = max(profile[name][i].Count, block.Count)
+			continue
+		}
+		seen[key] = len(profile[name])
+		profile[name] = append(profile[name], block)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// parseCoverLine parses "name.go:10.2,12.16 3 1".
+func parseCoverLine(line string) (string, CoverBlock, error) {
+	var b CoverBlock
+	colon := strings.LastIndex(line, ":")
+	if colon < 0 {
+		return "", b, fmt.Errorf("malformed coverage line %q", line)
+	}
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return "", b, fmt.Errorf("malformed coverage line %q", line)
+	}
+	start, end, ok := strings.Cut(fields[0], ",")
+	if !ok {
+		return "", b, fmt.Errorf("malformed coverage range %q", fields[0])
+	}
+	var err error
+	if b.StartLine, err = coverLine(start); err != nil {
+		return "", b, err
+	}
+	if b.EndLine, err = coverLine(end); err != nil {
+		return "", b, err
+	}
+	if b.Stmts, err = strconv.Atoi(fields[1]); err != nil {
+		return "", b, err
+	}
+	if b.Count, err = strconv.Atoi(fields[2]); err != nil {
+		return "", b, err
+	}
+	return line[:colon], b, nil
+}
+
+// coverLine returns the line of a "line.column" position.
+func coverLine(pos string) (int, error) {
+	line, _, _ := strings.Cut(pos, ".")
+	return strconv.Atoi(line)
+}
+
+// ApplyCoverProfile sets Covered and CoveragePct on function chunks from the
+// blocks inside their line range. Functions without statements, and files
+// the profile does not mention, are left unset.
+func ApplyCoverProfile(chunks []Chunk, profile CoverProfile) {
+	for i := range chunks {
+		md := &chunks[i].Metadata
+		if md.Kind != "function" || md.StartLine == 0 {
+			continue
+		}
+		blocks := profile[md.ImportPath+"/"+path.Base(md.Path)]
+		total, covered := 0, 0
+		for _, b := range blocks {
+			if b.StartLine < md.StartLine || b.EndLine > md.EndLine {
+				continue
+			}
+			total += b.Stmts
+			if b.Count > 0 {
+				covered += b.Stmts
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		isCovered := covered > 0
+		pct := float64(covered) * 100 / float64(total)
+		md.Covered = &isCovered
+		md.CoveragePct = &pct
+	}
+}
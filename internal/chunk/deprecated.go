@@ -0,0 +1,45 @@
+package chunk
+
+import (
+	"go/ast"
+	"strings"
+)
+
+const deprecatedMarker = "Deprecated:"
+
+// deprecationNote finds a "Deprecated:" paragraph in doc text, following the
+// Go convention that the marker starts a paragraph. It returns the text after
+// the marker, with continued lines joined, and whether the marker was found.
+// Matching is case-sensitive.
+func deprecationNote(doc string) (string, bool) {
+	for _, para := range strings.Split(doc, "\n\n") {
+		para = strings.TrimSpace(para)
+		if !strings.HasPrefix(para, deprecatedMarker) {
+			continue
+		}
+		note := strings.TrimPrefix(para, deprecatedMarker)
+		return strings.Join(strings.Fields(note), " "), true
+	}
+	return "", false
+}
+
+// setDeprecation marks md as deprecated when the declaration's doc comments
+// carry a "Deprecated:" paragraph. It reads the comments directly so the tag
+// is set even when DocPolicy leaves the doc out of the chunk text.
+func setDeprecation(md *Metadata, groups ...*ast.CommentGroup) {
+	if note, ok := deprecationNote(gatherDoc(groups...)); ok {
+		md.Deprecated = true
+		md.DeprecationNote = note
+	}
+}
+
+// packageDeprecated reports whether any file's package comment marks the
+// package as deprecated.
+func packageDeprecated(files []*ast.File) bool {
+	for _, file := range files {
+		if _, ok := deprecationNote(commentText(file.Doc)); ok {
+			return true
+		}
+	}
+	return false
+}
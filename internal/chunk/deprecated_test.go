@@ -0,0 +1,42 @@
+package chunk
+
+import "testing"
+
+const deprecatedPkgSrc = `// Package old does things the old way.
+//
+// Deprecated: use example.com/new instead.
+package old
+
+// Do does it.
+func Do() {}
+`
+
+func TestDeprecatedPackage(t *testing.T) {
+	chunks := buildFiles(t, map[string]string{"a.go": deprecatedPkgSrc}, Options{})
+	if len(chunks) == 0 {
+		t.Fatal("no chunks")
+	}
+	for _, ch := range chunks {
+		if !ch.Metadata.PackageDeprecated {
+			t.Errorf("chunk %s not tagged PackageDeprecated", ch.ID)
+		}
+	}
+
+	coverage := NewCoverage()
+	skipped := buildFiles(t, map[string]string{"a.go": deprecatedPkgSrc}, Options{SkipDeprecatedPackages: true, Coverage: coverage})
+	if len(skipped) != 0 {
+		t.Errorf("SkipDeprecatedPackages kept %d chunks", len(skipped))
+	}
+	if coverage.Emitted != 0 || coverage.ExcludedFor(ExcludedDeprecated) != 1 {
+		t.Errorf("coverage = %+v, want the one declaration excluded as deprecated", coverage)
+	}
+}
+
+func TestUndeprecatedPackage(t *testing.T) {
+	src := "// Package fine mentions that Deprecated: markers matter only as paragraphs.\npackage fine\n\n// Do does it.\nfunc Do() {}\n"
+	for _, ch := range buildFiles(t, map[string]string{"a.go": src}, Options{SkipDeprecatedPackages: true}) {
+		if ch.Metadata.PackageDeprecated {
+			t.Errorf("chunk %s tagged PackageDeprecated", ch.ID)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+)
+
+// buildDirectiveChunk collects a file's //go:generate, //go:build and other
+// tool directives, verbatim, into a single "directive" chunk. Directives
+// inside function bodies are ignored. Doc comment text drops directives, so
+// this is the only place a //go:generate above a type survives.
+func buildDirectiveChunk(fc *fileContext) []Chunk {
+	file := fc.file
+	var lines []string
+	var first, last *ast.Comment
+	for _, group := range file.Comments {
+		if insideFuncBody(file, group) {
+			continue
+		}
+		for _, c := range group.List {
+			if !isDirective(c.Text) {
+				continue
+			}
+			lines = append(lines, c.Text)
+			if first == nil {
+				first = c
+			}
+			last = c
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	name := filepath.Base(fc.fset.Position(file.Package).Filename)
+	return []Chunk{{
+		ID:   fc.id(fmt.Sprintf("%s:%s:directives", fc.path, file.Name.Name), "directive", name),
+		Text: strings.Join(lines, "\n"),
+		Metadata: Metadata{
+			Path:          fc.path,
+			PackageName:   file.Name.Name,
+			ImportPath:    fc.src.ImportPath,
+			ModulePath:    fc.src.ModulePath,
+			ModuleVersion: fc.src.ModuleVersion,
+			Kind:          "directive",
+			Source:        string(fc.src.Kind),
+			StartLine:     fc.line(first.Pos()),
+			EndLine:       fc.line(last.End()),
+		},
+	}}
+}
+
+// isDirective reports whether a // comment is a tool directive, using the
+// same rule as go/ast: "//line " or "//name:arg" with a lower-case name.
+func isDirective(text string) bool {
+	c, ok := strings.CutPrefix(text, "//")
+	if !ok {
+		return false
+	}
+	if strings.HasPrefix(c, "line ") {
+		return true
+	}
+	colon := strings.Index(c, ":")
+	if colon <= 0 || colon+1 >= len(c) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := c[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// insideFuncBody reports whether group lies within a function body.
+func insideFuncBody(file *ast.File, group *ast.CommentGroup) bool {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if ok && fn.Body != nil && group.Pos() > fn.Body.Lbrace && group.End() < fn.Body.Rbrace {
+			return true
+		}
+	}
+	return false
+}
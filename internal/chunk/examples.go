@@ -0,0 +1,91 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// collectMethodExamples reads the _test.go files in dir and returns the
+// source of each ExampleT_M function (and ExampleT_M_suffix variant) keyed
+// by "T.M", in name order. Files that cannot be read or parsed are skipped;
+// examples are an extra and never fail a build.
+func collectMethodExamples(dir string, opts Options) map[string][]string {
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(files)
+
+	type example struct{ name, code string }
+	found := make(map[string][]example)
+	for _, file := range files {
+		if opts.MaxFileBytes > 0 {
+			if info, err := os.Stat(file); err != nil || info.Size() > opts.MaxFileBytes {
+				continue
+			}
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil {
+				continue
+			}
+			key := exampleMethod(fn.Name.Name)
+			if key == "" {
+				continue
+			}
+			found[key] = append(found[key], example{fn.Name.Name, extractSnippet(fset, content, fn.Pos(), fn.End())})
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	examples := make(map[string][]string, len(found))
+	for key, list := range found {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].name < list[j].name })
+		for _, ex := range list {
+			examples[key] = append(examples[key], ex.code)
+		}
+	}
+	return examples
+}
+
+// exampleMethod returns "T.M" for an example function named ExampleT_M or
+// ExampleT_M_suffix, and "" for any other name. As in go doc, a part that
+// starts with a lower-case letter is a suffix, so ExampleT_second is an
+// example of the type T rather than a method.
+func exampleMethod(name string) string {
+	rest, ok := strings.CutPrefix(name, "Example")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(rest, "_")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !startsUpper(parts[1]) {
+		return ""
+	}
+	if len(parts) == 3 && (parts[2] == "" || startsUpper(parts[2])) {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
+
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,143 @@
+package chunk
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strconv"
+)
+
+// buildFieldChunks emits one chunk per named or embedded field of a struct type.
+func buildFieldChunks(fc *fileContext, spec *ast.TypeSpec) []Chunk {
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok || st.Fields == nil || !fc.opts.includeKind("field") {
+		return nil
+	}
+
+	var chunks []Chunk
+	for _, field := range st.Fields.List {
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, embeddedName(field.Type))
+		}
+
+		var extra map[string]string
+		if field.Tag != nil {
+			if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+				for key, value := range parseStructTag(tag) {
+					if extra == nil {
+						extra = make(map[string]string)
+					}
+					extra[key+"_tag"] = value
+				}
+			}
+		}
+
+		var buf bytes.Buffer
+		if fc.opts.includeDoc("field") {
+			if doc := gatherDoc(field.Doc, field.Comment); doc != "" {
+				buf.WriteString(doc)
+				buf.WriteString("\n\n")
+			}
+		}
+		fmt.Fprintf(&buf, "type %s struct {\n\t%s\n}", spec.Name.Name, extractSnippet(fc.fset, fc.content, field.Pos(), field.End()))
+
+		for _, name := range names {
+			if fc.opts.ExportedOnly && !ast.IsExported(name) {
+				continue
+			}
+			qualified := spec.Name.Name + "." + name
+			if !fc.opts.matchSymbol(name, qualified) || !fc.opts.OnlySymbols.allow(qualified) {
+				continue
+			}
+			chunks = append(chunks, Chunk{
+				ID:   fc.id(fmt.Sprintf("%s:field:%s", fc.path, qualified), "field", qualified),
+				Text: buf.String(),
+				Metadata: Metadata{
+					Path:          fc.path,
+					PackageName:   fc.pkg,
+					ImportPath:    fc.src.ImportPath,
+					ModulePath:    fc.src.ModulePath,
+					ModuleVersion: fc.src.ModuleVersion,
+					Symbol:        fmt.Sprintf("field %s", qualified),
+					Kind:          "field",
+					Source:        string(fc.src.Kind),
+					StartLine:     fc.line(field.Pos()),
+					EndLine:       fc.line(field.End()),
+					Extra:         extra,
+				},
+			})
+		}
+	}
+	return chunks
+}
+
+// embeddedName returns the field name Go assigns to an embedded field.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return exprString(expr)
+	}
+}
+
+// parseStructTag splits a struct tag into its key/value pairs using the same
+// scanning rules as reflect.StructTag.Lookup. Scanning stops at the first
+// malformed pair, keeping whatever was parsed before it.
+func parseStructTag(tag string) map[string]string {
+	pairs := make(map[string]string)
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		if _, dup := pairs[key]; !dup {
+			pairs[key] = value
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,52 @@
+package chunk
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestParseStructTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want map[string]string
+	}{
+		{``, map[string]string{}},
+		{`json:"name"`, map[string]string{"json": "name"}},
+		{`json:"name,omitempty"`, map[string]string{"json": "name,omitempty"}},
+		{`json:"name,omitempty" db:"user_name" yaml:"name"`, map[string]string{"json": "name,omitempty", "db": "user_name", "yaml": "name"}},
+		{`json:"-"`, map[string]string{"json": "-"}},
+		{`json:",omitempty,string"`, map[string]string{"json": ",omitempty,string"}},
+		{`validate:"min=1,max=\"10\""`, map[string]string{"validate": `min=1,max="10"`}},
+		{`json:"a"  db:"b"`, map[string]string{"json": "a", "db": "b"}},
+		{`json:"a" json:"b"`, map[string]string{"json": "a"}},
+		{`json:"a" broken db:"b"`, map[string]string{"json": "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got := parseStructTag(tt.tag)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseStructTag(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+			// Every parsed key agrees with reflect's own lookup.
+			for key, value := range got {
+				if want, _ := reflect.StructTag(tt.tag).Lookup(key); want != value {
+					t.Errorf("key %s = %q, reflect gives %q", key, value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldChunkTags(t *testing.T) {
+	src := "package m\n\ntype User struct {\n\tName string `json:\"name,omitempty\" db:\"user_name\"`\n\tAge  int\n}\n"
+	chunks := buildFiles(t, map[string]string{"a.go": src}, Options{FieldChunks: true})
+	name := chunkByID(t, chunks, "a.go:field:User.Name")
+	want := map[string]string{"json_tag": "name,omitempty", "db_tag": "user_name"}
+	if !maps.Equal(name.Metadata.Extra, want) {
+		t.Errorf("Name Extra = %v, want %v", name.Metadata.Extra, want)
+	}
+	if age := chunkByID(t, chunks, "a.go:field:User.Age"); age.Metadata.Extra != nil {
+		t.Errorf("untagged Age has Extra %v", age.Metadata.Extra)
+	}
+}
@@ -0,0 +1,88 @@
+package chunk
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+const filterSrc = `package m
+
+// Handler handles.
+type Handler struct {
+	Route string
+}
+
+// ServeHTTP serves.
+func (h *Handler) ServeHTTP() {}
+
+// Close closes.
+func (h *Handler) Close() {}
+
+// NewHandler makes a Handler.
+func NewHandler() *Handler { return nil }
+
+// Run runs.
+func Run() {}
+
+const (
+	HandlerLimit, OtherLimit = 1, 2
+)
+`
+
+// declIDs returns the IDs of chunks that are not file or package docs.
+func declIDs(chunks []Chunk) []string {
+	var ids []string
+	for _, ch := range chunks {
+		switch ch.Metadata.Kind {
+		case "file-doc", "package-doc":
+			continue
+		}
+		ids = append(ids, ch.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestSymbolFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{
+			name:    "case-sensitive misses lower case",
+			pattern: "handler",
+			want:    nil,
+		},
+		{
+			name:    "case-insensitive matches types, receivers and values",
+			pattern: "(?i)handler",
+			want:    []string{"a.go:Close", "a.go:NewHandler", "a.go:ServeHTTP", "a.go:const:HandlerLimit,OtherLimit", "a.go:field:Handler.Route", "a.go:type:Handler"},
+		},
+		{
+			name:    "receiver-qualified method name",
+			pattern: `^Handler\.ServeHTTP$`,
+			want:    []string{"a.go:ServeHTTP"},
+		},
+		{
+			name:    "bare method name",
+			pattern: "^Close$",
+			want:    []string{"a.go:Close"},
+		},
+		{
+			name:    "qualified field name",
+			pattern: `^Handler\.Route$`,
+			want:    []string{"a.go:field:Handler.Route"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{FieldChunks: true, SymbolFilter: regexp.MustCompile(tt.pattern)}
+			got := declIDs(buildFiles(t, map[string]string{"a.go": filterSrc}, opts))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
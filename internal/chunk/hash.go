@@ -0,0 +1,26 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// contentHash returns the first 16 hex digits of the SHA-256 of text. It
+// depends on nothing but the text, so identical chunks hash alike across
+// builds and machines.
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// hashContents sets Metadata.ContentHash on every chunk. It wraps the final
+// transform of a build, so the hash covers the text as it is written out.
+func hashContents(chunks []Chunk, err error) ([]Chunk, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range chunks {
+		chunks[i].Metadata.ContentHash = contentHash(chunks[i].Text)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,112 @@
+package chunk
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// idPlaceholder matches a {name} placeholder in an ID template.
+var idPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// IDTemplate rewrites chunk IDs from a template such as
+// "{importPath}/{kind}/{symbol}", for vector stores with ID constraints.
+// Placeholders are {path}, {symbol}, {kind}, {importPath} and {hash}, the
+// first 16 hex digits of the SHA-256 of the chunk text.
+type IDTemplate struct {
+	template string
+}
+
+// ParseIDTemplate checks that template uses only known placeholders.
+func ParseIDTemplate(template string) (*IDTemplate, error) {
+	if strings.TrimSpace(template) == "" {
+		return nil, fmt.Errorf("idTemplate: empty template")
+	}
+	for _, p := range idPlaceholder.FindAllString(template, -1) {
+		switch p {
+		case "{path}", "{symbol}", "{kind}", "{importPath}", "{hash}":
+		default:
+			return nil, fmt.Errorf("idTemplate: unknown placeholder %s (want {path}, {symbol}, {kind}, {importPath} or {hash})", p)
+		}
+	}
+	return &IDTemplate{template: template}, nil
+}
+
+// Apply sets the ID of every chunk from the template and updates the
+// package-toc links in package-doc and package-toc text, and the content
+// hashes of those chunks, to match. It fails when two chunks end up with the
+// same ID, naming both, since stores silently overwrite on collision. Split
+// parts and chunks without a symbol usually need {hash} or {path} to stay
+// unique.
+func (t *IDTemplate) Apply(chunks []Chunk) error {
+	ids := make([]string, len(chunks))
+	seen := make(map[string]int, len(chunks))
+	for i, ch := range chunks {
+		md := ch.Metadata
+		id := idPlaceholder.ReplaceAllStringFunc(t.template, func(p string) string {
+			switch p {
+			case "{path}":
+				return md.Path
+			case "{symbol}":
+				return symbolName(md)
+			case "{kind}":
+				return md.Kind
+			case "{importPath}":
+				return md.ImportPath
+			}
+			return contentHash(ch.Text)
+		})
+		if j, dup := seen[id]; dup {
+			return fmt.Errorf("idTemplate: %s and %s both become %q; add {hash} or {path} to the template", chunks[j].ID, ch.ID, id)
+		}
+		seen[id] = i
+		ids[i] = id
+	}
+
+	// Package-doc chunks name their toc shards by ID; replace longer IDs
+	// first so toc-part-1 never matches inside toc-part-10.
+	var shards []int
+	for i, ch := range chunks {
+		if ch.Metadata.Kind == "package-toc" {
+			shards = append(shards, i)
+		}
+	}
+	sort.SliceStable(shards, func(a, b int) bool { return len(chunks[shards[a]].ID) > len(chunks[shards[b]].ID) })
+	var links []string
+	for _, i := range shards {
+		links = append(links, chunks[i].ID, ids[i])
+	}
+	replacer := strings.NewReplacer(links...)
+	for i := range chunks {
+		chunks[i].ID = ids[i]
+		if kind := chunks[i].Metadata.Kind; len(links) > 0 && (kind == "package-doc" || kind == "package-toc") {
+			chunks[i].Text = replacer.Replace(chunks[i].Text)
+			chunks[i].Metadata.ContentHash = contentHash(chunks[i].Text)
+		}
+	}
+	return nil
+}
+
+// symbolName returns the bare declared name of a chunk's symbol:
+// "Server.Serve" for "func (s *Server) Serve", "Server" for "type Server"
+// and "a, b" for "const a, b". Chunks that describe a declaration rather
+// than declare it keep their kind, as in "Server~type-bundle", so they never
+// take the declaration's name. Package-level chunks have none.
+func symbolName(md Metadata) string {
+	kind, name, ok := strings.Cut(md.Symbol, " ")
+	if !ok {
+		return md.Symbol
+	}
+	switch kind {
+	case "func", "type", "const", "var", "field":
+	default:
+		return name + "~" + kind
+	}
+	if md.ReceiverType != "" {
+		if i := strings.LastIndex(name, ") "); i >= 0 {
+			name = md.ReceiverType + "." + name[i+2:]
+		}
+	}
+	return name
+}
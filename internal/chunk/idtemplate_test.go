@@ -0,0 +1,70 @@
+package chunk
+
+import "testing"
+
+func TestSymbolName(t *testing.T) {
+	tests := []struct {
+		md   Metadata
+		want string
+	}{
+		{Metadata{Symbol: "func Run", Kind: "function"}, "Run"},
+		{Metadata{Symbol: "func (s *Server) Serve", Kind: "function", ReceiverType: "Server"}, "Server.Serve"},
+		{Metadata{Symbol: "type Server", Kind: "type"}, "Server"},
+		{Metadata{Symbol: "const a, b", Kind: "const"}, "a, b"},
+		{Metadata{Symbol: "field Server.Name", Kind: "field"}, "Server.Name"},
+		{Metadata{Symbol: "type-bundle Server", Kind: "type-bundle"}, "Server~type-bundle"},
+		{Metadata{Symbol: "usage Run", Kind: "usage"}, "Run~usage"},
+		{Metadata{Symbol: "example.com/dep", Kind: "module-info"}, "example.com/dep"},
+		{Metadata{Kind: "package-doc"}, ""},
+	}
+	for _, tt := range tests {
+		if got := symbolName(tt.md); got != tt.want {
+			t.Errorf("symbolName(%q) = %q, want %q", tt.md.Symbol, got, tt.want)
+		}
+	}
+}
+
+const bundleSrc = `package m
+
+// Server serves.
+type Server struct{}
+
+// Start starts.
+func (s *Server) Start() {}
+`
+
+func TestIDTemplateTypeBundle(t *testing.T) {
+	chunks := buildFiles(t, map[string]string{"a.go": bundleSrc}, Options{TypeBundles: true, Kinds: map[string]bool{"type": true, "type-bundle": true}})
+	bundle := chunkByID(t, chunks, "a.go:type-bundle:Server")
+	if bundle.Metadata.Symbol != "type-bundle Server" {
+		t.Errorf("bundle Symbol = %q, want a symbol distinct from the type's", bundle.Metadata.Symbol)
+	}
+	tmpl, err := ParseIDTemplate("{importPath}/{symbol}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Apply(chunks); err != nil {
+		t.Fatalf("bundle and type collided: %v", err)
+	}
+}
+
+func TestIDTemplateUsage(t *testing.T) {
+	src := "package m\n\n// Run runs.\nfunc Run() {}\n\nfunc main() { Run() }\n"
+	chunks := buildFiles(t, map[string]string{"a.go": src}, Options{UsageExamples: 1, Kinds: map[string]bool{"function": true, "usage": true}})
+	var usage []Chunk
+	for _, ch := range chunks {
+		if ch.Metadata.Kind == "usage" {
+			usage = append(usage, ch)
+		}
+	}
+	if len(usage) != 1 || usage[0].Metadata.Symbol != "usage Run" {
+		t.Fatalf("usage chunks = %+v, want one with symbol %q", usage, "usage Run")
+	}
+	tmpl, err := ParseIDTemplate("{importPath}/{symbol}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Apply(chunks); err != nil {
+		t.Fatalf("usage and function collided: %v", err)
+	}
+}
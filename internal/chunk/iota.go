@@ -0,0 +1,165 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strings"
+)
+
+// constValues resolves the values of a const declaration's specs that the
+// source leaves implicit: specs repeating the previous expression list and
+// specs whose expressions use iota. The result holds, per spec, one
+// rendered value per name, or nil when the spec's value is already spelled
+// out or cannot be evaluated without type information.
+func constValues(decl *ast.GenDecl) [][]string {
+	if decl.Tok != token.CONST {
+		return nil
+	}
+	out := make([][]string, len(decl.Specs))
+	env := make(map[string]constant.Value)
+	var last []ast.Expr
+	for i, spec := range decl.Specs {
+		s, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		exprs, implicit := s.Values, len(s.Values) == 0
+		if implicit {
+			exprs = last
+		} else {
+			last = s.Values
+		}
+		if len(exprs) != len(s.Names) {
+			continue
+		}
+
+		values := make([]string, len(s.Names))
+		resolved := true
+		for j, name := range s.Names {
+			v, ok := evalConst(exprs[j], int64(i), env)
+			if !ok {
+				resolved = false
+				continue
+			}
+			if name.Name != "_" {
+				env[name.Name] = v
+			}
+			values[j] = v.ExactString()
+		}
+		if resolved && (implicit || usesIota(exprs)) {
+			out[i] = values
+		}
+	}
+	return out
+}
+
+// evalConst evaluates a constant expression with the given iota, resolving
+// identifiers against constants declared earlier in the same group.
+func evalConst(expr ast.Expr, iota int64, env map[string]constant.Value) (constant.Value, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return v, v.Kind() != constant.Unknown
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(iota), true
+		case "true", "false":
+			return constant.MakeBool(e.Name == "true"), true
+		}
+		v, ok := env[e.Name]
+		return v, ok
+	case *ast.ParenExpr:
+		return evalConst(e.X, iota, env)
+	case *ast.UnaryExpr:
+		x, ok := evalConst(e.X, iota, env)
+		if !ok {
+			return nil, false
+		}
+		switch e.Op {
+		case token.ADD, token.SUB, token.XOR, token.NOT:
+			return constant.UnaryOp(e.Op, x, 0), true
+		}
+	case *ast.BinaryExpr:
+		x, ok := evalConst(e.X, iota, env)
+		if !ok {
+			return nil, false
+		}
+		y, ok := evalConst(e.Y, iota, env)
+		if !ok {
+			return nil, false
+		}
+		return evalBinary(e.Op, x, y)
+	case *ast.CallExpr:
+		// A conversion such as Weekday(iota) keeps its operand's value;
+		// builtins like len need type information and are left alone.
+		if len(e.Args) != 1 || !isConversion(e.Fun) {
+			return nil, false
+		}
+		return evalConst(e.Args[0], iota, env)
+	}
+	return nil, false
+}
+
+func evalBinary(op token.Token, x, y constant.Value) (v constant.Value, ok bool) {
+	// constant panics on mismatched kinds; treat those as unresolvable.
+	defer func() {
+		if recover() != nil {
+			v, ok = nil, false
+		}
+	}()
+	switch op {
+	case token.SHL, token.SHR:
+		s, exact := constant.Uint64Val(y)
+		if !exact || s > 1023 {
+			return nil, false
+		}
+		return constant.Shift(x, op, uint(s)), true
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return constant.MakeBool(constant.Compare(x, op, y)), true
+	case token.QUO, token.REM:
+		if constant.Sign(y) == 0 {
+			return nil, false
+		}
+		if op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+			op = token.QUO_ASSIGN // integer division
+		}
+	}
+	return constant.BinaryOp(x, op, y), true
+}
+
+func isConversion(fun ast.Expr) bool {
+	switch f := fun.(type) {
+	case *ast.ParenExpr:
+		return isConversion(f.X)
+	case *ast.Ident:
+		switch f.Name {
+		case "len", "cap", "real", "imag", "complex", "min", "max":
+			return false
+		}
+		return true
+	case *ast.SelectorExpr:
+		pkg, ok := f.X.(*ast.Ident)
+		return ok && pkg.Name != "unsafe"
+	}
+	return false
+}
+
+func usesIota(exprs []ast.Expr) bool {
+	found := false
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// constComment renders resolved values as a trailing " // = ..." comment.
+func constComment(values []string) string {
+	return " // = " + strings.Join(values, ", ")
+}
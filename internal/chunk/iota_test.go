@@ -0,0 +1,97 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConstValues(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want [][]string
+	}{
+		{
+			name: "plain iota",
+			src:  "const (\n\tA = iota\n\tB\n\tC\n)",
+			want: [][]string{{"0"}, {"1"}, {"2"}},
+		},
+		{
+			name: "typed iota",
+			src:  "const (\n\tSunday Weekday = iota\n\tMonday\n\tTuesday\n)",
+			want: [][]string{{"0"}, {"1"}, {"2"}},
+		},
+		{
+			name: "conversion",
+			src:  "const (\n\tA = Weekday(iota + 1)\n\tB\n)",
+			want: [][]string{{"1"}, {"2"}},
+		},
+		{
+			name: "shift",
+			src:  "const (\n\t_ = 1 << (10 * iota)\n\tKB\n\tMB\n)",
+			want: [][]string{{"1"}, {"1024"}, {"1048576"}},
+		},
+		{
+			name: "skipped and explicit specs",
+			src:  "const (\n\tA = iota * 2\n\t_\n\tC\n\tD = 7\n\tE\n)",
+			want: [][]string{{"0"}, {"2"}, {"4"}, nil, {"7"}},
+		},
+		{
+			name: "earlier constants",
+			src:  "const (\n\tBase = 10\n\tNext = Base + iota\n\tLast\n)",
+			want: [][]string{nil, {"11"}, {"12"}},
+		},
+		{
+			name: "several names per spec",
+			src:  "const (\n\tA, B = iota, iota * 10\n\tC, D\n)",
+			want: [][]string{{"0", "0"}, {"1", "10"}},
+		},
+		{
+			name: "explicit values left alone",
+			src:  "const (\n\tA = 1\n\tB = \"x\"\n)",
+			want: [][]string{nil, nil},
+		},
+		{
+			name: "needs type information",
+			src:  "const (\n\tA = len(\"abc\") + iota\n\tB = unknown + iota\n)",
+			want: [][]string{nil, nil},
+		},
+		{
+			name: "division by zero",
+			src:  "const (\n\tA = 1 / iota\n)",
+			want: [][]string{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "a.go", "package m\n\n"+tt.src+"\n", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := constValues(file.Decls[0].(*ast.GenDecl))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("constValues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstValuesInChunks(t *testing.T) {
+	src := "package m\n\n// Level is a log level.\ntype Level int\n\nconst (\n\tDebug Level = iota\n\tInfo\n\tWarn\n)\n"
+	chunks := buildFiles(t, map[string]string{"a.go": src}, Options{})
+	var text string
+	for _, ch := range chunks {
+		if ch.Metadata.Kind == "const" {
+			text += ch.Text + "\n"
+		}
+	}
+	for _, want := range []string{"Info // = 1", "Warn // = 2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("const chunks lack %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package chunk
+
+import (
+	"strings"
+	"unicode"
+)
+
+// applySearchKeywords fills Metadata.SearchKeywords for declaration chunks
+// when opts.SearchKeywords is set.
+func applySearchKeywords(chunks []Chunk, opts Options) {
+	if !opts.SearchKeywords {
+		return
+	}
+	for i := range chunks {
+		if chunks[i].Metadata.Symbol == "" {
+			continue
+		}
+		var words []string
+		seen := make(map[string]bool)
+		for _, name := range SymbolNames(chunks[i].Metadata.Symbol) {
+			for _, word := range SplitIdentifier(name) {
+				if !seen[word] {
+					seen[word] = true
+					words = append(words, word)
+				}
+			}
+		}
+		chunks[i].Metadata.SearchKeywords = strings.Join(words, " ")
+	}
+}
+
+// SplitIdentifier breaks a Go identifier into lower-case words for keyword
+// search: "ServeHTTP" gives [serve http], "parseURLQuery" gives
+// [parse url query] and "max_retry_count" gives [max retry count]. Runs of
+// capitals are kept together as one acronym, and a trailing capital that
+// starts a new word ("HTTPServer") is split off, unless it is only followed
+// by a plural "s" ("IDs" is [ids]) or by a version-style tail of one
+// lower-case letter and digits ("IPv4Addr" is [ipv4 addr]). Digits stay with
+// the word before them, so "Int64" is [int64]. Dots and underscores separate
+// words.
+func SplitIdentifier(name string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '.':
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !pluralAcronym(runes, i+1) && !versionTail(runes, i+1)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// pluralAcronym reports whether runes[i] is a lone "s" closing a word, as in
+// the plural acronym "IDs".
+func pluralAcronym(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// versionTail reports whether runes[i] is a single lower-case letter followed
+// by a digit, as the "v4" of "IPv4".
+func versionTail(runes []rune, i int) bool {
+	return i+1 < len(runes) && unicode.IsLower(runes[i]) && unicode.IsDigit(runes[i+1])
+}
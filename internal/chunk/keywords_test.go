@@ -0,0 +1,51 @@
+package chunk
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Serve", []string{"serve"}},
+		{"parseQuery", []string{"parse", "query"}},
+		{"ServeHTTP", []string{"serve", "http"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"parseURLQuery", []string{"parse", "url", "query"}},
+		{"UserID", []string{"user", "id"}},
+		{"IDs", []string{"ids"}},
+		{"UserIDs", []string{"user", "ids"}},
+		{"Int64", []string{"int64"}},
+		{"IPv4Addr", []string{"ipv4", "addr"}},
+		{"parseIPv6", []string{"parse", "ipv6"}},
+		{"max_retry_count", []string{"max", "retry", "count"}},
+		{"MAX_RETRIES", []string{"max", "retries"}},
+		{"Client.Do", []string{"client", "do"}},
+		{"URL", []string{"url"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitIdentifier(tt.name); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitIdentifier(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySearchKeywords(t *testing.T) {
+	chunks := []Chunk{
+		{Metadata: Metadata{Symbol: "Server.ServeHTTP"}},
+		{Metadata: Metadata{Kind: "package-doc"}},
+	}
+	applySearchKeywords(chunks, Options{SearchKeywords: true})
+	if got, want := chunks[0].Metadata.SearchKeywords, "server serve http"; got != want {
+		t.Errorf("SearchKeywords = %q, want %q", got, want)
+	}
+	if got := chunks[1].Metadata.SearchKeywords; got != "" {
+		t.Errorf("package doc got SearchKeywords %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package chunk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ModuleSource is a dependency module whose go.mod is summarized by
+// BuildModuleInfo.
+type ModuleSource struct {
+	Path    string
+	Version string
+	// GoMod is the module's go.mod file, as reported by go list -m.
+	GoMod string
+	Kind  SourceKind
+}
+
+// BuildModuleInfo returns a "module-info" chunk stating the module's path,
+// version, go and toolchain directives and direct requirements, so questions
+// such as "which version of X does Y require" have a factual answer. It
+// returns nil when module-info chunks are not wanted or the module has no
+// go.mod. The chunk is enriched and transformed like any other.
+func BuildModuleInfo(mod ModuleSource, opts Options) ([]Chunk, error) {
+	if mod.GoMod == "" || !opts.includeKind("module-info") || opts.OnlySymbols != nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(mod.GoMod)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	file, err := modfile.ParseLax(mod.GoMod, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("module-info %s: %w", mod.Path, err)
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "module %s", mod.Path)
+	if mod.Version != "" {
+		fmt.Fprintf(&buf, "@%s", mod.Version)
+	}
+	buf.WriteString("\n")
+	extra := make(map[string]string)
+	if file.Go != nil {
+		fmt.Fprintf(&buf, "go %s\n", file.Go.Version)
+		extra["go"] = file.Go.Version
+	}
+	if file.Toolchain != nil {
+		fmt.Fprintf(&buf, "toolchain %s\n", file.Toolchain.Name)
+	}
+	direct, indirect := 0, 0
+	for _, req := range file.Require {
+		if req.Indirect {
+			indirect++
+			continue
+		}
+		if direct == 0 {
+			buf.WriteString("\nrequires:\n")
+		}
+		direct++
+		fmt.Fprintf(&buf, "\t%s %s\n", req.Mod.Path, req.Mod.Version)
+	}
+	if indirect > 0 {
+		fmt.Fprintf(&buf, "\nplus %d indirect requirement(s)\n", indirect)
+	}
+	extra["directRequires"] = strconv.Itoa(direct)
+
+	id := fmt.Sprintf("%s:go.mod:module-info", mod.Path)
+	if opts.StableIDs {
+		id = stableID(mod.Path, "module-info", "")
+	}
+	chunks := []Chunk{{
+		ID:   id,
+		Text: strings.TrimSpace(buf.String()),
+		Metadata: Metadata{
+			Path:          "go.mod",
+			ImportPath:    mod.Path,
+			ModulePath:    mod.Path,
+			ModuleVersion: mod.Version,
+			Symbol:        mod.Path,
+			Kind:          "module-info",
+			Source:        string(mod.Kind),
+			Extra:         extra,
+		},
+	}}
+	enrich(chunks, opts)
+	applyPathStyle(chunks, "", opts)
+	return hashContents(transform(chunks, opts.Transformers))
+}
@@ -0,0 +1,114 @@
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nativeExts are the non-Go source files the go tool compiles into a
+// package: assembly, and C, C++ and Objective-C for cgo.
+var nativeExts = map[string]string{
+	".s": "assembly", ".S": "assembly",
+	".c": "C", ".h": "C",
+	".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hh": "C++", ".hpp": "C++", ".hxx": "C++",
+	".m": "Objective-C",
+}
+
+// usesCgo reports whether file imports the cgo pseudo-package "C".
+func usesCgo(file *ast.File) bool {
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// fileDoc returns the package comment of file. In a cgo file, C
+// preprocessor lines such as "#include <stdio.h>" that strayed into the
+// comment are dropped, so C code never reaches file-doc or package-doc
+// chunks; the real preamble above import "C" is never a package comment.
+// Go doc headings ("# Title") are kept.
+func fileDoc(file *ast.File) string {
+	if file.Doc == nil || !usesCgo(file) {
+		return commentText(file.Doc)
+	}
+	// Filter before NormalizeDoc joins the lines into paragraphs.
+	lines := strings.Split(file.Doc.Text(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if len(line) > 1 && line[0] == '#' && line[1] >= 'a' && line[1] <= 'z' {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return NormalizeDoc(strings.TrimSpace(strings.Join(kept, "\n")))
+}
+
+// buildNativeNote returns a "native-code" chunk noting that the package
+// uses cgo or holds assembly or C sources, which are not chunked, or nil
+// when it is pure Go.
+func buildNativeNote(src PackageSource, files []*fileContext) []Chunk {
+	if len(files) == 0 {
+		return nil
+	}
+	var cgoFiles []string
+	for _, fc := range files {
+		if fc.testKind == "" && usesCgo(fc.file) {
+			cgoFiles = append(cgoFiles, filepath.Base(fc.path))
+		}
+	}
+	byLang := make(map[string][]string)
+	var langs []string
+	if entries, err := os.ReadDir(src.Dir); err == nil {
+		for _, entry := range entries {
+			lang, ok := nativeExts[filepath.Ext(entry.Name())]
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if byLang[lang] == nil {
+				langs = append(langs, lang)
+			}
+			byLang[lang] = append(byLang[lang], entry.Name())
+		}
+	}
+	if len(cgoFiles) == 0 && len(langs) == 0 {
+		return nil
+	}
+
+	pkg := files[0].pkg
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "package %s // import %q uses native code, which is not chunked.\n", pkg, src.ImportPath)
+	var uses []string
+	if len(cgoFiles) > 0 {
+		uses = append(uses, "cgo")
+		fmt.Fprintf(&buf, "\ncgo (import \"C\"): %s", strings.Join(cgoFiles, ", "))
+	}
+	for _, lang := range langs {
+		uses = append(uses, strings.ToLower(lang))
+		fmt.Fprintf(&buf, "\n%s sources: %s", lang, strings.Join(byLang[lang], ", "))
+	}
+
+	dirRel := relativePath(src.ModuleDir, src.Dir)
+	id := fmt.Sprintf("%s:%s:native-code", dirRel, pkg)
+	if files[0].opts.StableIDs {
+		id = stableID(src.ImportPath, "native-code", "")
+	}
+	return []Chunk{{
+		ID:   id,
+		Text: buf.String(),
+		Metadata: Metadata{
+			Path:          dirRel,
+			PackageName:   pkg,
+			ImportPath:    src.ImportPath,
+			ModulePath:    src.ModulePath,
+			ModuleVersion: src.ModuleVersion,
+			Kind:          "native-code",
+			Source:        string(src.Kind),
+			Extra:         map[string]string{"uses": strings.Join(uses, ",")},
+		},
+	}}
+}
@@ -0,0 +1,167 @@
+package chunk
+
+import (
+	"strings"
+	"unicode"
+)
+
+const fence = "```"
+
+// NormalizeDoc tidies doc comment text for embedding. Prose paragraphs are
+// unwrapped onto one line, list items each get a single line starting with
+// "- " or, for numbered lists, their own "1." or "2)" marker, indented
+// code blocks become fenced Markdown, stray leading "//" markers are removed
+// and runs of blank lines collapse to one. Text that is already fenced is
+// kept verbatim, so NormalizeDoc(NormalizeDoc(s)) == NormalizeDoc(s).
+func NormalizeDoc(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var blocks []string
+	var para []string
+	// inList is set while consecutive list items share one block, so a list
+	// is not spread over blank-line separated paragraphs.
+	inList := false
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, strings.Join(para, " "))
+			para = nil
+			inList = false
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRightFunc(lines[i], unicode.IsSpace)
+		if !isIndented(line) {
+			line = stripCommentMarker(line)
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, fence) && !isIndented(line):
+			flush()
+			block := []string{line}
+			for i++; i < len(lines); i++ {
+				block = append(block, strings.TrimRightFunc(lines[i], unicode.IsSpace))
+				if strings.TrimSpace(lines[i]) == fence {
+					break
+				}
+			}
+			blocks = append(blocks, strings.Join(block, "\n"))
+			inList = false
+		case listMarker(trimmed) != "":
+			flush()
+			indent := leadingWidth(line)
+			marker := listMarker(trimmed)
+			item := []string{itemPrefix(marker) + strings.TrimSpace(strings.TrimPrefix(trimmed, marker))}
+			for i+1 < len(lines) {
+				next := strings.TrimRightFunc(lines[i+1], unicode.IsSpace)
+				nextTrimmed := strings.TrimSpace(next)
+				if nextTrimmed == "" || listMarker(nextTrimmed) != "" || leadingWidth(next) <= indent {
+					break
+				}
+				item = append(item, nextTrimmed)
+				i++
+			}
+			if inList {
+				blocks[len(blocks)-1] += "\n" + strings.Join(item, " ")
+			} else {
+				blocks = append(blocks, strings.Join(item, " "))
+				inList = true
+			}
+		case isIndented(line):
+			flush()
+			code := []string{line}
+			for i+1 < len(lines) {
+				next := strings.TrimRightFunc(lines[i+1], unicode.IsSpace)
+				if next != "" && !isIndented(next) {
+					break
+				}
+				code = append(code, next)
+				i++
+			}
+			for len(code) > 0 && code[len(code)-1] == "" {
+				code = code[:len(code)-1]
+			}
+			blocks = append(blocks, fence+"\n"+strings.Join(dedent(code), "\n")+"\n"+fence)
+			inList = false
+		case strings.HasPrefix(trimmed, "# "):
+			flush()
+			blocks = append(blocks, trimmed)
+			inList = false
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+// stripCommentMarker removes a "//" left at the start of a line, as happens
+// with comments nested inside block comments.
+func stripCommentMarker(line string) string {
+	if rest, ok := strings.CutPrefix(line, "//"); ok {
+		return strings.TrimPrefix(rest, " ")
+	}
+	return line
+}
+
+// listMarker returns the bullet or number prefix, including the following
+// space, that starts a list item in trimmed, or "".
+func listMarker(trimmed string) string {
+	for _, bullet := range []string{"- ", "* ", "+ ", "• "} {
+		if strings.HasPrefix(trimmed, bullet) {
+			return bullet
+		}
+	}
+	digits := 0
+	for digits < len(trimmed) && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits+1 < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') && trimmed[digits+1] == ' ' {
+		return trimmed[:digits+2]
+	}
+	return ""
+}
+
+// itemPrefix returns the marker a normalized list item starts with: "- " for
+// any bullet, and the number with its "." or ")" kept for numbered items so
+// steps stay distinguishable.
+func itemPrefix(marker string) string {
+	if marker[0] >= '0' && marker[0] <= '9' {
+		return marker
+	}
+	return "- "
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// leadingWidth counts leading whitespace, with a tab as one column.
+func leadingWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// dedent strips the whitespace prefix shared by all non-blank lines.
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		ws := line[:leadingWidth(line)]
+		if first {
+			prefix, first = ws, false
+			continue
+		}
+		for !strings.HasPrefix(ws, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+	}
+	return out
+}
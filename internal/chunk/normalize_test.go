@@ -0,0 +1,68 @@
+package chunk
+
+import "testing"
+
+func TestNormalizeDoc(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "unwraps paragraphs",
+			in:   "Client is an HTTP client.\nIts zero value is usable.\n\nIt is safe for concurrent use.\n",
+			want: "Client is an HTTP client. Its zero value is usable.\n\nIt is safe for concurrent use.",
+		},
+		{
+			name: "collapses blank lines",
+			in:   "First.\n\n\n\nSecond.",
+			want: "First.\n\nSecond.",
+		},
+		{
+			name: "fences indented code",
+			in:   "Example:\n\n\tc := New()\n\tc.Do()\n\nDone.",
+			want: "Example:\n\n```\nc := New()\nc.Do()\n```\n\nDone.",
+		},
+		{
+			name: "keeps blank lines inside code",
+			in:   "\tif x {\n\n\t\ty()\n\t}",
+			want: "```\nif x {\n\n\ty()\n}\n```",
+		},
+		{
+			name: "bullets become dashes",
+			in:   "Options:\n  - first item\n    continues\n  * second item",
+			want: "Options:\n\n- first item continues\n- second item",
+		},
+		{
+			name: "numbered items keep their markers",
+			in:   "Steps:\n  1. open the file\n  2. read it\n     to the end\n  3) close it",
+			want: "Steps:\n\n1. open the file\n2. read it to the end\n3) close it",
+		},
+		{
+			name: "strips nested comment markers",
+			in:   "// Deprecated: use Bar.\n//\n// Foo does things.",
+			want: "Deprecated: use Bar.\n\nFoo does things.",
+		},
+		{
+			name: "headings stay on their own line",
+			in:   "# Usage\nCall Run.",
+			want: "# Usage\n\nCall Run.",
+		},
+		{
+			name: "existing fences are verbatim",
+			in:   "```\n  keep   this\n```",
+			want: "```\n  keep   this\n```",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeDoc(tt.in)
+			if got != tt.want {
+				t.Errorf("NormalizeDoc(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+			}
+			if again := NormalizeDoc(got); again != got {
+				t.Errorf("not idempotent: second pass gives\n%q", again)
+			}
+		})
+	}
+}
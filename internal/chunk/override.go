@@ -0,0 +1,27 @@
+package chunk
+
+// ModuleOverride sets the options that may differ between modules. A nil
+// field keeps the value of the enclosing Options.
+type ModuleOverride struct {
+	ExportedOnly *bool
+	IncludeTests *bool
+	RequireDoc   *bool
+}
+
+// forModule returns opts with the override for modulePath, if any, applied.
+func (o Options) forModule(modulePath string) Options {
+	ov, ok := o.ModuleOverrides[modulePath]
+	if !ok {
+		return o
+	}
+	if ov.ExportedOnly != nil {
+		o.ExportedOnly = *ov.ExportedOnly
+	}
+	if ov.IncludeTests != nil {
+		o.IncludeTests = *ov.IncludeTests
+	}
+	if ov.RequireDoc != nil {
+		o.RequireDoc = *ov.RequireDoc
+	}
+	return o
+}
@@ -0,0 +1,174 @@
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// buildPackageDoc merges the package comments of files into a single
+// "package-doc" chunk followed by a sorted index of exported functions and
+// types. When the result exceeds Options.MaxTOCBytes the index moves into
+// "package-toc" shards that link to each other. It returns nil when the
+// package has neither docs nor exports.
+func buildPackageDoc(src PackageSource, files []*ast.File, opts Options) []Chunk {
+	if len(files) == 0 {
+		return nil
+	}
+
+	var docs []string
+	funcs := make(map[string]struct{})
+	types := make(map[string]struct{})
+	for _, file := range files {
+		if doc := fileDoc(file); doc != "" {
+			docs = append(docs, doc)
+		}
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil && d.Name.IsExported() {
+					funcs[d.Name.Name] = struct{}{}
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.IsExported() {
+						types[ts.Name.Name] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	if len(docs) == 0 && len(funcs) == 0 && len(types) == 0 {
+		return nil
+	}
+
+	pkg := files[0].Name.Name
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "package %s // import %q", pkg, src.ImportPath)
+	if len(docs) > 0 {
+		buf.WriteString("\n\n")
+		buf.WriteString(strings.Join(docs, "\n\n"))
+	}
+	header := buf.String()
+	index := []tocSection{
+		{title: "Functions", names: sortedNames(funcs)},
+		{title: "Types", names: sortedNames(types)},
+	}
+
+	dirRel := relativePath(src.ModuleDir, src.Dir)
+	id := fmt.Sprintf("%s:%s:package-doc", dirRel, pkg)
+	if opts.StableIDs {
+		id = stableID(src.ImportPath, "package-doc", "")
+	}
+	base := Chunk{
+		Metadata: Metadata{
+			Path:          dirRel,
+			PackageName:   pkg,
+			ImportPath:    src.ImportPath,
+			ModulePath:    src.ModulePath,
+			ModuleVersion: src.ModuleVersion,
+			Kind:          "package-doc",
+			Source:        string(src.Kind),
+		},
+	}
+
+	full := header + renderTOC(index)
+	if opts.MaxTOCBytes <= 0 || len(full) <= opts.MaxTOCBytes {
+		doc := base
+		doc.ID = id
+		doc.Text = full
+		return []Chunk{doc}
+	}
+
+	// The index is too large for one chunk: keep the package doc on its own
+	// and shard the index into linked toc-part-N chunks.
+	shards := shardTOC(index, opts.MaxTOCBytes)
+	ids := make([]string, len(shards))
+	for i := range shards {
+		ids[i] = fmt.Sprintf("%s:toc-part-%d", id, i+1)
+	}
+
+	doc := base
+	doc.ID = id
+	doc.Text = fmt.Sprintf("%s\n\nSymbol index continues in: %s", header, strings.Join(ids, ", "))
+	chunks := []Chunk{doc}
+	for i, shard := range shards {
+		var text strings.Builder
+		fmt.Fprintf(&text, "package %s symbol index, part %d of %d", pkg, i+1, len(shards))
+		text.WriteString(renderTOC(shard))
+		if i > 0 {
+			fmt.Fprintf(&text, "\n\nPrevious: %s", ids[i-1])
+		}
+		if i+1 < len(shards) {
+			fmt.Fprintf(&text, "\n\nNext: %s", ids[i+1])
+		}
+
+		part := base
+		part.ID = ids[i]
+		part.Text = text.String()
+		part.Metadata.Kind = "package-toc"
+		part.Metadata.Part = i + 1
+		part.Metadata.Parts = len(shards)
+		chunks = append(chunks, part)
+	}
+	return chunks
+}
+
+// tocSection is one titled list of names in a package symbol index.
+type tocSection struct {
+	title string
+	names []string
+}
+
+func renderTOC(sections []tocSection) string {
+	var buf strings.Builder
+	for _, sec := range sections {
+		if len(sec.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n\n%s: %s", sec.title, strings.Join(sec.names, ", "))
+	}
+	return buf.String()
+}
+
+// shardTOC packs the names of sections, in order, into shards whose rendered
+// index stays within limit bytes where possible. A single name longer than
+// the limit still gets a shard of its own.
+func shardTOC(sections []tocSection, limit int) [][]tocSection {
+	var shards [][]tocSection
+	var current []tocSection
+	size := 0
+	for _, sec := range sections {
+		for _, name := range sec.names {
+			cost := len(name) + len(", ")
+			if len(current) == 0 || current[len(current)-1].title != sec.title {
+				cost = len("\n\n") + len(sec.title) + len(": ") + len(name)
+			}
+			if size > 0 && size+cost > limit {
+				shards = append(shards, current)
+				current, size = nil, 0
+				cost = len("\n\n") + len(sec.title) + len(": ") + len(name)
+			}
+			if len(current) == 0 || current[len(current)-1].title != sec.title {
+				current = append(current, tocSection{title: sec.title})
+			}
+			last := &current[len(current)-1]
+			last.names = append(last.names, name)
+			size += cost
+		}
+	}
+	if len(current) > 0 {
+		shards = append(shards, current)
+	}
+	return shards
+}
+
+func sortedNames(set map[string]struct{}) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
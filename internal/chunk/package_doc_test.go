@@ -0,0 +1,97 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// manyExports returns a package source with n exported functions and n
+// exported types.
+func manyExports(n int) string {
+	var src strings.Builder
+	src.WriteString("// Package m has a large symbol index.\npackage m\n")
+	for i := range n {
+		fmt.Fprintf(&src, "\nfunc Function%03d() {}\n\ntype Type%03d struct{}\n", i, i)
+	}
+	return src.String()
+}
+
+func TestPackageTOCShards(t *testing.T) {
+	const n = 40
+	tests := []struct {
+		name       string
+		limit      int
+		wantShards bool
+	}{
+		{name: "disabled", limit: 0},
+		{name: "fits", limit: 1 << 20},
+		{name: "sharded", limit: 120, wantShards: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := buildFiles(t, map[string]string{"a.go": manyExports(n)}, Options{MaxTOCBytes: tt.limit})
+			doc := chunkByID(t, chunks, ".:m:package-doc")
+			var parts []Chunk
+			for _, ch := range chunks {
+				if ch.Metadata.Kind == "package-toc" {
+					parts = append(parts, ch)
+				}
+			}
+			if !tt.wantShards {
+				if len(parts) != 0 {
+					t.Fatalf("got %d toc shards, want none", len(parts))
+				}
+				if !strings.Contains(doc.Text, "Function039") || !strings.Contains(doc.Text, "Type000") {
+					t.Errorf("package-doc is missing its index:\n%s", doc.Text)
+				}
+				return
+			}
+
+			if len(parts) < 2 {
+				t.Fatalf("got %d toc shards, want several", len(parts))
+			}
+			if strings.Contains(doc.Text, "Function000") {
+				t.Errorf("package-doc still holds the index:\n%s", doc.Text)
+			}
+			seen := make(map[string]int)
+			for i, part := range parts {
+				wantID := fmt.Sprintf(".:m:package-doc:toc-part-%d", i+1)
+				if part.ID != wantID {
+					t.Errorf("shard %d ID = %q, want %q", i, part.ID, wantID)
+				}
+				if part.Metadata.Part != i+1 || part.Metadata.Parts != len(parts) {
+					t.Errorf("%s: part %d of %d, want %d of %d", part.ID, part.Metadata.Part, part.Metadata.Parts, i+1, len(parts))
+				}
+				if !strings.Contains(doc.Text, part.ID) {
+					t.Errorf("package-doc does not link %s", part.ID)
+				}
+				if i > 0 && !strings.Contains(part.Text, "Previous: "+parts[i-1].ID) {
+					t.Errorf("%s does not link back to %s", part.ID, parts[i-1].ID)
+				}
+				if i+1 < len(parts) && !strings.Contains(part.Text, "Next: "+fmt.Sprintf(".:m:package-doc:toc-part-%d", i+2)) {
+					t.Errorf("%s does not link forward", part.ID)
+				}
+				index, _, _ := strings.Cut(part.Text, "\n\nPrevious: ")
+				index, _, _ = strings.Cut(index, "\n\nNext: ")
+				_, index, _ = strings.Cut(index, fmt.Sprintf("part %d of %d", i+1, len(parts)))
+				if len(index) > tt.limit {
+					t.Errorf("%s index is %d bytes, over the %d limit", part.ID, len(index), tt.limit)
+				}
+				for _, line := range strings.Split(strings.TrimSpace(index), "\n\n") {
+					_, names, _ := strings.Cut(line, ": ")
+					for _, name := range strings.Split(names, ", ") {
+						seen[name]++
+					}
+				}
+			}
+			for i := range n {
+				for _, name := range []string{fmt.Sprintf("Function%03d", i), fmt.Sprintf("Type%03d", i)} {
+					if seen[name] != 1 {
+						t.Errorf("%s appears in %d shards, want 1", name, seen[name])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package chunk
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Path styles accepted by Options.PathStyle.
+const (
+	// PathModuleRelative records Metadata.Path relative to the module root
+	// (GOROOT/src for the standard library). It is the default.
+	PathModuleRelative = "module-relative"
+	// PathImportPath records the import path joined with the file name,
+	// or the bare import path for package-level chunks.
+	PathImportPath = "import-path"
+	// PathRepoRelative records paths relative to Options.Root. Files outside
+	// it, such as module cache and GOROOT sources, keep their
+	// module-relative path.
+	PathRepoRelative = "repo-relative"
+)
+
+// applyPathStyle rewrites the module-relative Metadata.Path of chunks
+// according to opts.PathStyle. moduleDir is the directory the paths are
+// relative to; it may be empty when unknown, leaving repo-relative paths
+// as they are. It runs after source URLs are expanded, so {path} in URL
+// templates stays module-relative and the links keep working.
+func applyPathStyle(chunks []Chunk, moduleDir string, opts Options) {
+	switch opts.PathStyle {
+	case PathImportPath:
+		for i := range chunks {
+			md := &chunks[i].Metadata
+			if md.Kind == "package-doc" || md.Kind == "package-toc" || md.Kind == "native-code" || md.Kind == "command" {
+				md.Path = md.ImportPath
+			} else {
+				md.Path = path.Join(md.ImportPath, path.Base(md.Path))
+			}
+		}
+	case PathRepoRelative:
+		if moduleDir == "" || opts.Root == "" {
+			return
+		}
+		for i := range chunks {
+			md := &chunks[i].Metadata
+			rel, err := filepath.Rel(opts.Root, filepath.Join(moduleDir, filepath.FromSlash(md.Path)))
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			md.Path = filepath.ToSlash(rel)
+		}
+	}
+}
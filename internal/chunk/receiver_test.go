@@ -0,0 +1,59 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+const receiverSrc = `package m
+
+// Server serves.
+type Server struct{}
+
+// Start mutates the server.
+func (s *Server) Start() {}
+
+// Addr reads the server.
+func (s Server) Addr() string { return "" }
+
+// Stop mutates the server.
+func (*Server) Stop() {}
+
+// Box holds a value.
+type Box[T any] struct{ v T }
+
+// Get reads the box.
+func (b Box[T]) Get() T { return b.v }
+
+// Set mutates the box.
+func (b *Box[T]) Set(v T) { b.v = v }
+`
+
+func TestReceiverKinds(t *testing.T) {
+	chunks := buildFiles(t, map[string]string{"a.go": receiverSrc}, Options{TypeBundles: true})
+	tests := []struct {
+		id, wantType, wantKind string
+	}{
+		{"a.go:Start", "Server", "pointer"},
+		{"a.go:Addr", "Server", "value"},
+		{"a.go:Stop", "Server", "pointer"},
+		{"a.go:Get", "Box", "value"},
+		{"a.go:Set", "Box", "pointer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			md := chunkByID(t, chunks, tt.id).Metadata
+			if md.ReceiverType != tt.wantType || md.ReceiverKind != tt.wantKind {
+				t.Errorf("receiver = %s/%s, want %s/%s", md.ReceiverType, md.ReceiverKind, tt.wantType, tt.wantKind)
+			}
+		})
+	}
+
+	// The bundle groups pointer and value methods under the one type.
+	bundle := chunkByID(t, chunks, "a.go:type-bundle:Server")
+	for _, method := range []string{"Start", "Addr", "Stop"} {
+		if !strings.Contains(bundle.Text, method) {
+			t.Errorf("Server bundle lacks %s:\n%s", method, bundle.Text)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package chunk
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// DefaultSkipFilePatterns are the file name patterns of generated code that
+// is skipped unless Options.ReplaceSkipPatterns drops them.
+var DefaultSkipFilePatterns = []string{"*_mock.go", "*_generated.go", "*.pb.go", "*_pb2.go"}
+
+// skipPatterns returns the file name patterns in effect: the defaults
+// extended by SkipFilePatterns, or SkipFilePatterns alone when
+// ReplaceSkipPatterns is set.
+func (o Options) skipPatterns() []string {
+	if o.ReplaceSkipPatterns {
+		return o.SkipFilePatterns
+	}
+	return append(DefaultSkipFilePatterns[:len(DefaultSkipFilePatterns):len(DefaultSkipFilePatterns)], o.SkipFilePatterns...)
+}
+
+// ShouldSkipFile reports whether a Go source file named name is left out
+// of a build with the default options: a _test.go file or one matching
+// DefaultSkipFilePatterns.
+func ShouldSkipFile(name string) bool {
+	return matchSkip(name, DefaultSkipFilePatterns)
+}
+
+// skipFile reports whether the source file at file, named name, is left
+// out: a _test.go file, a name matching the skip patterns, or, with
+// SkipGenerated, a file with a "Code generated ... DO NOT EDIT." header.
+func (o Options) skipFile(file, name string) bool {
+	return matchSkip(name, o.skipPatterns()) || (o.SkipGenerated && isGeneratedFile(file))
+}
+
+func matchSkip(name string, patterns []string) bool {
+	if strings.HasSuffix(name, "_test.go") {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedFile reports whether the file at path carries the standard
+// generated-code header before its package clause.
+func isGeneratedFile(path string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly|parser.ParseComments)
+	return err == nil && ast.IsGenerated(file)
+}
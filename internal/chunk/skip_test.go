@@ -0,0 +1,64 @@
+package chunk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldSkipFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"server.go", false},
+		{"server_test.go", true},
+		{"store_mock.go", true},
+		{"zz_generated.go", true},
+		{"api.pb.go", true},
+		{"api_pb2.go", true},
+		{"mockery.go", false},
+		{"generated_types.go", false},
+		{"pb.go", false},
+	}
+	for _, tt := range tests {
+		if got := ShouldSkipFile(tt.name); got != tt.want {
+			t.Errorf("ShouldSkipFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipFileOptions(t *testing.T) {
+	dir := t.TempDir()
+	generated := filepath.Join(dir, "gen.go")
+	if err := os.WriteFile(generated, []byte("// Code generated by stringer. DO NOT EDIT.\n\npackage m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain.go")
+	if err := os.WriteFile(plain, []byte("package m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		file string
+		want bool
+	}{
+		{name: "defaults match ShouldSkipFile", file: "api.pb.go", want: true},
+		{name: "extra pattern", opts: Options{SkipFilePatterns: []string{"*.gen.go"}}, file: "types.gen.go", want: true},
+		{name: "extra keeps defaults", opts: Options{SkipFilePatterns: []string{"*.gen.go"}}, file: "store_mock.go", want: true},
+		{name: "replace drops defaults", opts: Options{SkipFilePatterns: []string{"*.gen.go"}, ReplaceSkipPatterns: true}, file: "store_mock.go"},
+		{name: "replace still skips tests", opts: Options{ReplaceSkipPatterns: true}, file: "a_test.go", want: true},
+		{name: "generated header", opts: Options{SkipGenerated: true}, file: generated, want: true},
+		{name: "generated header ignored by default", file: generated},
+		{name: "plain file with SkipGenerated", opts: Options{SkipGenerated: true}, file: plain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.skipFile(tt.file, filepath.Base(tt.file)); got != tt.want {
+				t.Errorf("skipFile(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package chunk
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// applySourceURLs fills Metadata.SourceURL for chunks whose source kind has a
+// template in opts.SourceURLs.
+func applySourceURLs(chunks []Chunk, opts Options) {
+	if len(opts.SourceURLs) == 0 {
+		return
+	}
+	for i := range chunks {
+		md := &chunks[i].Metadata
+		tmpl, ok := opts.SourceURLs[SourceKind(md.Source)]
+		if !ok || tmpl == "" {
+			continue
+		}
+		md.SourceURL = expandSourceURL(tmpl, *md, sourceCommit(*md, opts.Commit))
+	}
+}
+
+// sourceCommit returns what {commit} means for md: the project's commit for
+// project chunks, the Go version, which names a tag of the Go repository,
+// for stdlib chunks, and the module version for third-party chunks.
+func sourceCommit(md Metadata, projectCommit string) string {
+	switch SourceKind(md.Source) {
+	case SourceProject:
+		if projectCommit != "" {
+			return projectCommit
+		}
+	case SourceStdlib:
+		return runtime.Version()
+	}
+	return md.ModuleVersion
+}
+
+// expandSourceURL substitutes {commit}, {path}, {start}, {end}, {module},
+// {version} and {importPath} in tmpl; see sourceCommit for {commit}.
+// Chunks without a line span, such as package docs, drop the URL fragment
+// so no bogus line anchor is produced.
+func expandSourceURL(tmpl string, md Metadata, commit string) string {
+	if md.StartLine == 0 {
+		if i := strings.Index(tmpl, "#"); i >= 0 {
+			tmpl = tmpl[:i]
+		}
+	}
+	return strings.NewReplacer(
+		"{commit}", commit,
+		"{path}", md.Path,
+		"{start}", strconv.Itoa(md.StartLine),
+		"{end}", strconv.Itoa(md.EndLine),
+		"{module}", md.ModulePath,
+		"{version}", md.ModuleVersion,
+		"{importPath}", md.ImportPath,
+	).Replace(tmpl)
+}
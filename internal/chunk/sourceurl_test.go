@@ -0,0 +1,61 @@
+package chunk
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestApplySourceURLs(t *testing.T) {
+	const tmpl = "https://example.com/{commit}/{path}#L{start}-L{end}"
+	opts := Options{
+		Commit: "abc123",
+		SourceURLs: map[SourceKind]string{
+			SourceProject:    tmpl,
+			SourceThirdParty: tmpl,
+			SourceStdlib:     tmpl,
+		},
+	}
+	tests := []struct {
+		name string
+		md   Metadata
+		want string
+	}{
+		{
+			name: "project uses the commit",
+			md:   Metadata{Source: string(SourceProject), Path: "a.go", StartLine: 3, EndLine: 5},
+			want: "https://example.com/abc123/a.go#L3-L5",
+		},
+		{
+			name: "third-party uses its module version",
+			md:   Metadata{Source: string(SourceThirdParty), ModuleVersion: "v1.2.3", Path: "b.go", StartLine: 1, EndLine: 2},
+			want: "https://example.com/v1.2.3/b.go#L1-L2",
+		},
+		{
+			name: "stdlib uses the Go version",
+			md:   Metadata{Source: string(SourceStdlib), Path: "net/http/server.go", StartLine: 10, EndLine: 20},
+			want: "https://example.com/" + runtime.Version() + "/net/http/server.go#L10-L20",
+		},
+		{
+			name: "no line span drops the fragment",
+			md:   Metadata{Source: string(SourceProject), Path: "."},
+			want: "https://example.com/abc123/.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := []Chunk{{Metadata: tt.md}}
+			applySourceURLs(chunks, opts)
+			if got := chunks[0].Metadata.SourceURL; got != tt.want {
+				t.Errorf("SourceURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySourceURLsProjectWithoutCommit(t *testing.T) {
+	chunks := []Chunk{{Metadata: Metadata{Source: string(SourceProject), ModuleVersion: "v0.1.0", Path: "a.go", StartLine: 1, EndLine: 1}}}
+	applySourceURLs(chunks, Options{SourceURLs: map[SourceKind]string{SourceProject: "{commit}/{path}"}})
+	if got, want := chunks[0].Metadata.SourceURL, "v0.1.0/a.go"; got != want {
+		t.Errorf("SourceURL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package chunk
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// SplitTextWithOverlap splits text into pieces of at most max tokens, where
+// consecutive pieces share overlap tokens. Tokens are runs of non-whitespace,
+// a cheap approximation of embedding-model tokens. Pieces are sliced from the
+// original text, so indentation and line breaks inside a piece are preserved.
+// Text within the limit, or a non-positive max, yields a single piece.
+func SplitTextWithOverlap(text string, max, overlap int) []string {
+	spans := tokenSpans(text)
+	if max <= 0 || len(spans) <= max {
+		return []string{text}
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	if overlap >= max {
+		overlap = max - 1
+	}
+
+	var parts []string
+	for start := 0; ; start += max - overlap {
+		end := start + max
+		if end > len(spans) {
+			end = len(spans)
+		}
+		parts = append(parts, text[spans[start][0]:spans[end-1][1]])
+		if end == len(spans) {
+			break
+		}
+	}
+	return parts
+}
+
+// tokenSpans returns the byte offsets [start, end) of each token in text.
+func tokenSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// split breaks ch into parts when its body, the text after the first headLen
+// bytes, exceeds MaxTokens. The head (import preamble and doc comment) is kept
+// on the first part only so overlap never repeats it.
+func (fc *fileContext) split(ch Chunk, headLen int) []Chunk {
+	if fc.opts.MaxTokens <= 0 {
+		return []Chunk{ch}
+	}
+	head, body := ch.Text[:headLen], ch.Text[headLen:]
+	pieces := SplitTextWithOverlap(body, fc.opts.MaxTokens, fc.opts.ChunkOverlap)
+	if len(pieces) == 1 {
+		return []Chunk{ch}
+	}
+
+	parts := make([]Chunk, len(pieces))
+	for i, piece := range pieces {
+		part := ch
+		part.ID = fmt.Sprintf("%s:part-%d", ch.ID, i+1)
+		part.Text = piece
+		if i == 0 {
+			part.Text = head + piece
+		}
+		part.Metadata.Part = i + 1
+		part.Metadata.Parts = len(pieces)
+		parts[i] = part
+	}
+	return parts
+}
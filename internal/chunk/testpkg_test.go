@@ -0,0 +1,63 @@
+package chunk
+
+import (
+	"slices"
+	"testing"
+)
+
+var testPackageFiles = map[string]string{
+	"m.go":           "package m\n\n// Run runs.\nfunc Run() {}\n",
+	"m_test.go":      "package m\n\n// TestRun tests Run from inside.\nfunc TestRun() {}\n",
+	"extern_test.go": "package m_test\n\n// ExampleRun shows Run.\nfunc ExampleRun() {}\n",
+}
+
+func TestTestPackageKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]string // function chunk ID to TestPackageKind
+	}{
+		{
+			name: "tests excluded",
+			want: map[string]string{"m.go:Run": ""},
+		},
+		{
+			name: "both kinds",
+			opts: Options{IncludeTests: true},
+			want: map[string]string{"m.go:Run": "", "m_test.go:TestRun": TestPackageInternal, "extern_test.go:ExampleRun": TestPackageExternal},
+		},
+		{
+			name: "internal only",
+			opts: Options{IncludeTests: true, TestPackages: TestPackageInternal},
+			want: map[string]string{"m.go:Run": "", "m_test.go:TestRun": TestPackageInternal},
+		},
+		{
+			name: "external only",
+			opts: Options{IncludeTests: true, TestPackages: TestPackageExternal},
+			want: map[string]string{"m.go:Run": "", "extern_test.go:ExampleRun": TestPackageExternal},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			for _, ch := range buildFiles(t, testPackageFiles, tt.opts) {
+				if ch.Metadata.Kind == "function" {
+					got[ch.ID] = ch.Metadata.TestPackageKind
+				}
+			}
+			if len(got) != len(tt.want) {
+				ids := make([]string, 0, len(got))
+				for id := range got {
+					ids = append(ids, id)
+				}
+				slices.Sort(ids)
+				t.Fatalf("function chunks = %v, want %v", ids, tt.want)
+			}
+			for id, kind := range tt.want {
+				if gotKind, ok := got[id]; !ok || gotKind != kind {
+					t.Errorf("%s: TestPackageKind = %q (present %v), want %q", id, gotKind, ok, kind)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package chunk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// TopicRule assigns Topic to chunks whose symbol name matches the glob
+// Pattern (path.Match syntax, e.g. "*Repository").
+type TopicRule struct {
+	Pattern string `json:"pattern"`
+	Topic   string `json:"topic"`
+}
+
+// LoadTopicRules reads an ordered JSON array of topic rules from file and
+// checks that every pattern is a valid glob.
+func LoadTopicRules(file string) ([]TopicRule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("topic map: %w", err)
+	}
+	var rules []TopicRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("topic map %s: %w", file, err)
+	}
+	for _, rule := range rules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("topic map %s: pattern %q: %w", file, rule.Pattern, err)
+		}
+	}
+	return rules, nil
+}
+
+// applyTopics sets Metadata.Topic from the first rule in opts.Topics that
+// matches one of a chunk's names. Methods and fields are tried as
+// Type.Name and then by their type alone, so "*Handler" also tags the
+// methods of UserHandler.
+func applyTopics(chunks []Chunk, opts Options) {
+	if len(opts.Topics) == 0 {
+		return
+	}
+	for i := range chunks {
+		if chunks[i].Metadata.Symbol == "" {
+			continue
+		}
+		var candidates []string
+		for _, name := range SymbolNames(chunks[i].Metadata.Symbol) {
+			candidates = append(candidates, name)
+			if owner, _, ok := strings.Cut(name, "."); ok {
+				candidates = append(candidates, owner)
+			}
+		}
+	rules:
+		for _, rule := range opts.Topics {
+			for _, name := range candidates {
+				if ok, _ := path.Match(rule.Pattern, name); ok {
+					chunks[i].Metadata.Topic = rule.Topic
+					break rules
+				}
+			}
+		}
+	}
+}
+
+// SymbolNames extracts the declared names from a metadata symbol such as
+// "func (s *Server) Run", "type Server" or "const A, B". Methods are reported
+// as Receiver.Method.
+func SymbolNames(symbol string) []string {
+	kind, rest, ok := strings.Cut(symbol, " ")
+	if !ok {
+		return []string{symbol}
+	}
+	if kind == "func" && strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")"); end > 0 {
+			recv := strings.TrimSpace(rest[1:end])
+			if i := strings.LastIndex(recv, " "); i >= 0 {
+				recv = recv[i+1:]
+			}
+			recv = strings.TrimPrefix(recv, "*")
+			if i := strings.Index(recv, "["); i >= 0 {
+				recv = recv[:i]
+			}
+			return []string{recv + "." + strings.TrimSpace(rest[end+1:])}
+		}
+	}
+	names := strings.Split(rest, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+package chunk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const topicsSrc = `package m
+
+// UserRepository stores users.
+type UserRepository struct{}
+
+// Save saves a user.
+func (r *UserRepository) Save() {}
+
+// UserHandler serves users.
+type UserHandler struct{}
+
+// ServeHTTP serves a request.
+func (h *UserHandler) ServeHTTP() {}
+
+// NewUserHandler returns a handler.
+func NewUserHandler() *UserHandler { return nil }
+
+// Parse parses input.
+func Parse() {}
+`
+
+func TestTopics(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []TopicRule
+		want  map[string]string
+	}{
+		{
+			name: "several patterns",
+			rules: []TopicRule{
+				{Pattern: "*Repository", Topic: "persistence"},
+				{Pattern: "*Handler", Topic: "api"},
+			},
+			want: map[string]string{
+				"a.go:type:UserRepository": "persistence",
+				"a.go:Save":                "persistence",
+				"a.go:type:UserHandler":    "api",
+				"a.go:ServeHTTP":           "api",
+				"a.go:NewUserHandler":      "api",
+				"a.go:Parse":               "",
+			},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []TopicRule{
+				{Pattern: "User*", Topic: "users"},
+				{Pattern: "*Handler", Topic: "api"},
+				{Pattern: "*", Topic: "other"},
+			},
+			want: map[string]string{
+				"a.go:type:UserRepository": "users",
+				"a.go:type:UserHandler":    "users",
+				"a.go:NewUserHandler":      "api",
+				"a.go:Parse":               "other",
+			},
+		},
+		{
+			name: "qualified method name before its type",
+			rules: []TopicRule{
+				{Pattern: "*.ServeHTTP", Topic: "http"},
+				{Pattern: "*Handler", Topic: "api"},
+			},
+			want: map[string]string{
+				"a.go:ServeHTTP":        "http",
+				"a.go:type:UserHandler": "api",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := buildFiles(t, map[string]string{"a.go": topicsSrc}, Options{Topics: tt.rules})
+			for id, want := range tt.want {
+				if got := chunkByID(t, chunks, id).Metadata.Topic; got != want {
+					t.Errorf("%s: Topic = %q, want %q", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTopicRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr string
+	}{
+		{name: "ordered rules", data: `[{"pattern": "*Repository", "topic": "persistence"}, {"pattern": "*Handler", "topic": "api"}]`, want: 2},
+		{name: "bad glob", data: `[{"pattern": "[", "topic": "x"}]`, wantErr: `pattern "["`},
+		{name: "not an array", data: `{"pattern": "*"}`, wantErr: "topic map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "topics.json")
+			if err := os.WriteFile(file, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := LoadTopicRules(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != tt.want || rules[0].Topic != "persistence" {
+				t.Errorf("rules = %+v", rules)
+			}
+		})
+	}
+}
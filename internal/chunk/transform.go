@@ -0,0 +1,85 @@
+package chunk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Transformer post-processes chunks as they are built, for example to redact
+// secrets or add metadata. Transform returns the chunk to keep, or false to
+// drop it; an error aborts the build.
+type Transformer interface {
+	Transform(Chunk) (Chunk, bool, error)
+}
+
+// TransformerFunc adapts a function to the Transformer interface.
+type TransformerFunc func(Chunk) (Chunk, bool, error)
+
+// Transform implements Transformer.
+func (f TransformerFunc) Transform(ch Chunk) (Chunk, bool, error) { return f(ch) }
+
+// transform runs chunks through transformers in order, dropping any chunk a
+// transformer rejects.
+func transform(chunks []Chunk, transformers []Transformer) ([]Chunk, error) {
+	if len(transformers) == 0 {
+		return chunks, nil
+	}
+	kept := chunks[:0]
+	for _, ch := range chunks {
+		keep := true
+		for _, t := range transformers {
+			var err error
+			if ch, keep, err = t.Transform(ch); err != nil {
+				return nil, fmt.Errorf("transform %s: %w", ch.ID, err)
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, ch)
+		}
+	}
+	return kept, nil
+}
+
+// Redactor replaces every match of Pattern in chunk text with Replacement,
+// which may refer to submatches as in regexp.ReplaceAllString.
+type Redactor struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Transform implements Transformer.
+func (r Redactor) Transform(ch Chunk) (Chunk, bool, error) {
+	ch.Text = r.Pattern.ReplaceAllString(ch.Text, r.Replacement)
+	return ch, true, nil
+}
+
+// ImportPathRewriter maps import path prefixes to replacements and rewrites
+// chunk import and module paths with the longest matching prefix, e.g. to
+// present a fork under its upstream path. Only whole path elements match, so
+// "example.com/a" does not rewrite "example.com/ab".
+type ImportPathRewriter map[string]string
+
+// Transform implements Transformer.
+func (r ImportPathRewriter) Transform(ch Chunk) (Chunk, bool, error) {
+	ch.Metadata.ImportPath = r.rewrite(ch.Metadata.ImportPath)
+	ch.Metadata.ModulePath = r.rewrite(ch.Metadata.ModulePath)
+	return ch, true, nil
+}
+
+func (r ImportPathRewriter) rewrite(p string) string {
+	best, bestRest := "", ""
+	for from := range r {
+		rest, ok := strings.CutPrefix(p, from)
+		if ok && (rest == "" || strings.HasPrefix(rest, "/")) && len(from) > len(best) {
+			best, bestRest = from, rest
+		}
+	}
+	if best == "" {
+		return p
+	}
+	return r[best] + bestRest
+}
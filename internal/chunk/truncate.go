@@ -0,0 +1,80 @@
+package chunk
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// Truncate keeps the first max chunks, so a sorted build yields the same
+// subset every time. With prioritize, chunks of exported declarations with a
+// doc comment are kept first, then exported or documented ones, then the
+// rest; package-doc, file-doc and module-info chunks rank with the first.
+// The kept chunks stay in their original order. A max of zero or less keeps
+// everything.
+func Truncate(chunks []Chunk, max int, prioritize bool) []Chunk {
+	if max <= 0 || len(chunks) <= max {
+		return chunks
+	}
+	if !prioritize {
+		return chunks[:max]
+	}
+	order := make([]int, len(chunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return priority(chunks[order[i]]) > priority(chunks[order[j]])
+	})
+	order = order[:max]
+	sort.Ints(order)
+	kept := make([]Chunk, len(order))
+	for i, idx := range order {
+		kept[i] = chunks[idx]
+	}
+	return kept
+}
+
+func priority(ch Chunk) int {
+	switch ch.Metadata.Kind {
+	case "package-doc", "file-doc", "module-info":
+		return 2
+	}
+	score := 0
+	if exportedSymbol(ch.Metadata) {
+		score++
+	}
+	if ch.documented {
+		score++
+	}
+	return score
+}
+
+// exportedSymbol reports whether the chunk's symbol ("func (r *T) Name",
+// "type T", "const a, B", "field T.F") names an exported declaration. A
+// method or field counts only when its type is exported too, and a value
+// group when any of its names is.
+func exportedSymbol(md Metadata) bool {
+	_, symbol, ok := strings.Cut(md.Symbol, " ")
+	if !ok {
+		return false
+	}
+	if md.ReceiverType != "" {
+		if !ast.IsExported(md.ReceiverType) {
+			return false
+		}
+		if i := strings.LastIndex(symbol, ") "); i >= 0 {
+			symbol = symbol[i+2:]
+		}
+	}
+	for _, name := range strings.Split(symbol, ", ") {
+		exported := true
+		for _, part := range strings.Split(name, ".") {
+			exported = exported && ast.IsExported(part)
+		}
+		if exported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package chunk
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultTypeBundleBytes bounds a type-bundle chunk when
+// Options.TypeBundleMaxBytes is zero.
+const defaultTypeBundleBytes = 4096
+
+// bundleType is an exported type declaration and the file it is in.
+type bundleType struct {
+	fc   *fileContext
+	decl *ast.GenDecl
+	spec *ast.TypeSpec
+}
+
+// buildTypeBundles emits a "type-bundle" chunk for each exported type of the
+// package that has exported methods: its doc, its declaration and the
+// signatures of its methods from every file, sorted by name, so one chunk
+// answers "everything about Server". A bundle over the size bound collapses
+// the struct or interface body to a count, then keeps only the first
+// paragraph of the doc, then lists only the method signatures that fit.
+// Test files are not bundled.
+func buildTypeBundles(files []*fileContext, opts Options) []Chunk {
+	var types []bundleType
+	methods := make(map[string][]string)
+	for _, fc := range files {
+		if fc.testKind != "" {
+			continue
+		}
+		for _, decl := range fc.file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil || !d.Name.IsExported() {
+					continue
+				}
+				if recv, _ := receiverBase(d.Recv.List); ast.IsExported(recv) {
+					methods[recv] = append(methods[recv], funcSignature(d))
+				}
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					if ts := spec.(*ast.TypeSpec); ts.Name.IsExported() {
+						types = append(types, bundleType{fc: fc, decl: d, spec: ts})
+					}
+				}
+			}
+		}
+	}
+
+	limit := opts.TypeBundleMaxBytes
+	if limit <= 0 {
+		limit = defaultTypeBundleBytes
+	}
+	var chunks []Chunk
+	for _, t := range types {
+		name := t.spec.Name.Name
+		sigs := methods[name]
+		if len(sigs) == 0 || !opts.matchSymbol(name) || !opts.OnlySymbols.allow(name) {
+			continue
+		}
+		sort.Strings(sigs)
+		fc := t.fc
+
+		var head strings.Builder
+		if opts.includeDoc("type") {
+			doc := commentText(t.spec.Doc)
+			if doc == "" && len(t.decl.Specs) == 1 {
+				doc = commentText(t.decl.Doc)
+			}
+			if doc != "" {
+				head.WriteString(doc)
+				head.WriteString("\n\n")
+			}
+		}
+		doc := head.String()
+		decl := "type " + extractSnippet(fc.fset, fc.content, t.spec.Pos(), t.spec.End())
+		text := renderBundle(doc, decl, sigs, len(sigs))
+		if len(text) > limit {
+			decl = collapsedType(t.spec)
+			text = renderBundle(doc, decl, sigs, len(sigs))
+		}
+		if first, _, cut := strings.Cut(doc, "\n\n"); cut && len(text) > limit && first+"\n\n" != doc {
+			doc = first + "\n\n"
+			text = renderBundle(doc, decl, sigs, len(sigs))
+		}
+		for n := len(sigs) - 1; len(text) > limit && n > 0; n-- {
+			text = renderBundle(doc, decl, sigs[:n], len(sigs))
+		}
+
+		chunks = append(chunks, Chunk{
+			ID:   fc.id(fmt.Sprintf("%s:type-bundle:%s", fc.path, name), "type-bundle", name),
+			Text: text,
+			Metadata: Metadata{
+				Path:          fc.path,
+				PackageName:   fc.pkg,
+				ImportPath:    fc.src.ImportPath,
+				ModulePath:    fc.src.ModulePath,
+				ModuleVersion: fc.src.ModuleVersion,
+				Symbol:        fmt.Sprintf("type-bundle %s", name),
+				Kind:          "type-bundle",
+				Source:        string(fc.src.Kind),
+				Extra:         map[string]string{"methods": strconv.Itoa(len(sigs))},
+			},
+			documented: t.spec.Doc != nil || t.decl.Doc != nil,
+		})
+	}
+	return chunks
+}
+
+// renderBundle joins a bundle's doc, declaration and the method signatures
+// in sigs, noting how many of total were left out.
+func renderBundle(doc, decl string, sigs []string, total int) string {
+	var buf strings.Builder
+	buf.WriteString(doc)
+	buf.WriteString(decl)
+	buf.WriteString("\n\n// Methods:\n")
+	buf.WriteString(strings.Join(sigs, "\n"))
+	if left := total - len(sigs); left > 0 {
+		fmt.Fprintf(&buf, "\n// ... and %d more", left)
+	}
+	return buf.String()
+}
+
+// collapsedType renders spec as a declaration with any struct or interface
+// body replaced by a count of its fields or methods.
+func collapsedType(spec *ast.TypeSpec) string {
+	short := *spec
+	short.Doc, short.Comment = nil, nil
+	var body string
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		short.Type = ast.NewIdent("struct")
+		body = fmt.Sprintf(" { /* %d field(s) */ }", t.Fields.NumFields())
+	case *ast.InterfaceType:
+		short.Type = ast.NewIdent("interface")
+		body = fmt.Sprintf(" { /* %d method(s) */ }", t.Methods.NumFields())
+	}
+	var buf bytes.Buffer
+	if err := formatNode(&buf, &short); err != nil {
+		return "type " + spec.Name.Name
+	}
+	return "type " + buf.String() + body
+}
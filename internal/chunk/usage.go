@@ -0,0 +1,333 @@
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// usageMaxLines caps a call snippet. A longer enclosing statement is
+// replaced by the call itself, which is cut to this many lines.
+const usageMaxLines = 6
+
+// usageDef is an exported package-level function a usage chunk describes.
+type usageDef struct {
+	src  PackageSource
+	path string
+	pkg  string
+	name string
+	sig  string
+}
+
+// callSite is one call of a usageDef found in project source.
+type callSite struct {
+	path   string
+	line   int
+	caller string
+	text   string
+}
+
+// buildUsageChunks emits a "usage" chunk for each exported package-level
+// function in the project sources that is called elsewhere in them, holding
+// up to opts.UsageExamples call snippets. Calls are matched by name only: a
+// pkg.Func selector through the file's imports, or a bare Func within the
+// same package. Methods are not resolved. Call sites in _test.go files count,
+// since tests are often the clearest examples; generated files do not.
+// Files that cannot be read or parsed are skipped, as the main build has
+// already reported them.
+func buildUsageChunks(sources []PackageSource, opts Options) []Chunk {
+	if opts.UsageExamples <= 0 || !opts.includeKind("usage") {
+		return nil
+	}
+
+	defs := make(map[string]usageDef)
+	var keys []string
+	sites := make(map[string][]callSite)
+	for _, src := range sources {
+		if src.Kind != SourceProject {
+			continue
+		}
+		for _, file := range usageFiles(src, opts) {
+			fset := token.NewFileSet()
+			content, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			f, err := parser.ParseFile(fset, file, content, 0)
+			if err != nil {
+				continue
+			}
+			rel := relativePath(src.ModuleDir, file)
+			if !strings.HasSuffix(file, "_test.go") {
+				for _, decl := range f.Decls {
+					fn, ok := decl.(*ast.FuncDecl)
+					if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+						continue
+					}
+					key := src.ImportPath + "." + fn.Name.Name
+					if _, dup := defs[key]; dup {
+						continue
+					}
+					defs[key] = usageDef{src: src, path: rel, pkg: f.Name.Name, name: fn.Name.Name, sig: funcSignature(fn)}
+					keys = append(keys, key)
+				}
+			}
+			for key, found := range collectCalls(src, rel, fset, content, f) {
+				sites[key] = append(sites[key], found...)
+			}
+		}
+	}
+
+	var chunks []Chunk
+	for _, key := range keys {
+		def, calls := defs[key], sites[key]
+		if len(calls) == 0 || !opts.matchSymbol(def.name) || !opts.OnlySymbols.allow(def.name) {
+			continue
+		}
+		chunks = append(chunks, usageChunk(def, calls, opts))
+	}
+	if opts.StableIDs {
+		uniqueIDs(chunks)
+	}
+	return chunks
+}
+
+// usageFiles lists the Go files of src scanned for definitions and calls,
+// sorted, leaving out generated files and those over opts.MaxFileBytes.
+func usageFiles(src PackageSource, opts Options) []string {
+	entries, err := os.ReadDir(src.Dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		file := filepath.Join(src.Dir, name)
+		if !strings.HasSuffix(name, "_test.go") && opts.skipFile(file, name) {
+			continue
+		}
+		if opts.MaxFileBytes > 0 {
+			if info, err := entry.Info(); err == nil && info.Size() > opts.MaxFileBytes {
+				continue
+			}
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// collectCalls returns the calls in file keyed by the package-qualified name
+// of the function they call ("example.com/pkg.Func"). Recursive calls are
+// left out.
+func collectCalls(src PackageSource, rel string, fset *token.FileSet, content []byte, file *ast.File) map[string][]callSite {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		if name := importName(spec); name != "_" && name != "." {
+			imports[name] = strings.Trim(spec.Path.Value, `"`)
+		}
+	}
+	// An external test package refers to its own declarations by bare name,
+	// not to those of the package under test.
+	samePackage := !strings.HasSuffix(file.Name.Name, "_test")
+
+	calls := make(map[string][]callSite)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		caller := fn.Name.Name
+		if fn.Recv != nil {
+			if recv, _ := receiverBase(fn.Recv.List); recv != "" {
+				caller = recv + "." + caller
+			}
+		}
+		var stmts []ast.Stmt
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if n == nil {
+				stmts = stmts[:len(stmts)-1]
+				return true
+			}
+			var stmt ast.Stmt
+			if s, ok := n.(ast.Stmt); ok {
+				stmt = s
+			} else if len(stmts) > 0 {
+				stmt = stmts[len(stmts)-1]
+			}
+			stmts = append(stmts, stmt)
+
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			key := calleeKey(call.Fun, imports, src.ImportPath, samePackage)
+			if key == "" || (fn.Recv == nil && key == src.ImportPath+"."+fn.Name.Name) {
+				return true
+			}
+			node := ast.Node(call)
+			if stmt != nil && !isBlock(stmt) && fset.Position(stmt.End()).Line-fset.Position(stmt.Pos()).Line < usageMaxLines {
+				node = stmt
+			}
+			calls[key] = append(calls[key], callSite{
+				path:   rel,
+				line:   fset.Position(call.Pos()).Line,
+				caller: caller,
+				text:   sourceLines(fset, content, node.Pos(), node.End()),
+			})
+			return true
+		})
+	}
+	return calls
+}
+
+// calleeKey returns the package-qualified name a call's function expression
+// refers to, or "" when it is not a plain package-level function.
+func calleeKey(fun ast.Expr, imports map[string]string, importPath string, samePackage bool) string {
+	for {
+		switch f := fun.(type) {
+		case *ast.ParenExpr:
+			fun = f.X
+			continue
+		case *ast.IndexExpr:
+			fun = f.X
+			continue
+		case *ast.IndexListExpr:
+			fun = f.X
+			continue
+		case *ast.SelectorExpr:
+			// Package qualifiers are never resolved to a local object by the parser.
+			ident, ok := f.X.(*ast.Ident)
+			if !ok || ident.Obj != nil {
+				return ""
+			}
+			if path, ok := imports[ident.Name]; ok {
+				return path + "." + f.Sel.Name
+			}
+			return ""
+		case *ast.Ident:
+			// Functions declared in other files of the package are left
+			// unresolved; local variables and parameters are not.
+			if !samePackage || (f.Obj != nil && f.Obj.Kind != ast.Fun) {
+				return ""
+			}
+			return importPath + "." + f.Name
+		}
+		return ""
+	}
+}
+
+// isBlock reports whether stmt is a compound statement whose body would
+// swamp the call it contains.
+func isBlock(stmt ast.Stmt) bool {
+	switch stmt.(type) {
+	case *ast.BlockStmt, *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt,
+		*ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt,
+		*ast.CaseClause, *ast.CommClause, *ast.LabeledStmt:
+		return true
+	}
+	return false
+}
+
+// sourceLines returns the whole lines spanning start to end, with their
+// common indentation removed and at most usageMaxLines kept.
+func sourceLines(fset *token.FileSet, content []byte, start, end token.Pos) string {
+	from := fset.Position(start).Offset
+	to := fset.Position(end).Offset
+	for from > 0 && content[from-1] != '\n' {
+		from--
+	}
+	lines := strings.Split(strings.TrimRight(string(content[from:to]), " \t\n"), "\n")
+	cut := len(lines) > usageMaxLines
+	if cut {
+		lines = lines[:usageMaxLines]
+	}
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	if cut {
+		lines = append(lines, "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// usageChunk renders up to opts.UsageExamples of calls for def, taking one
+// call from each file in turn so the examples show different callers.
+func usageChunk(def usageDef, calls []callSite, opts Options) Chunk {
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].path != calls[j].path {
+			return calls[i].path < calls[j].path
+		}
+		return calls[i].line < calls[j].line
+	})
+	var picked []callSite
+	taken := make([]bool, len(calls))
+	for len(picked) < opts.UsageExamples && len(picked) < len(calls) {
+		lastPath := "\x00"
+		for i, c := range calls {
+			if taken[i] || c.path == lastPath || len(picked) == opts.UsageExamples {
+				continue
+			}
+			taken[i] = true
+			lastPath = c.path
+			picked = append(picked, c)
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		if picked[i].path != picked[j].path {
+			return picked[i].path < picked[j].path
+		}
+		return picked[i].line < picked[j].line
+	})
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "// Usage of %s.%s\n%s\n", def.pkg, def.name, def.sig)
+	if len(picked) < len(calls) {
+		fmt.Fprintf(&buf, "// %d of %d call sites:\n", len(picked), len(calls))
+	}
+	for _, c := range picked {
+		fmt.Fprintf(&buf, "\n// %s:%d in %s\n%s\n", c.path, c.line, c.caller, c.text)
+	}
+
+	id := fmt.Sprintf("%s:%s:usage", def.path, def.name)
+	if opts.StableIDs {
+		id = stableID(def.src.ImportPath, "usage", def.name)
+	}
+	return Chunk{
+		ID:   id,
+		Text: strings.TrimRight(buf.String(), "\n"),
+		Metadata: Metadata{
+			Path:          def.path,
+			PackageName:   def.pkg,
+			ImportPath:    def.src.ImportPath,
+			ModulePath:    def.src.ModulePath,
+			ModuleVersion: def.src.ModuleVersion,
+			Symbol:        "usage " + def.name,
+			Signature:     def.sig,
+			Kind:          "usage",
+			Source:        string(def.src.Kind),
+			Extra:         map[string]string{"callSites": strconv.Itoa(len(calls))},
+		},
+	}
+}
@@ -1,28 +1,310 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	// DefaultFile is the default filename written to the project root.
 	DefaultFile = ".go-rag-pack.json"
+	// DefaultGoListRetries rides out brief proxy or network outages in CI.
+	DefaultGoListRetries = 2
+	// DefaultMaxTOCBytes keeps package symbol indexes within typical embedding limits.
+	DefaultMaxTOCBytes = 6000
+	// GlobalFile is the machine-wide config name inside the user config directory.
+	GlobalFile = "config.json"
 )
 
 // Config captures persisted user preferences across select/build runs.
 type Config struct {
-	IncludeProject  bool     `json:"includeProject"`
-	IncludeStdlib   bool     `json:"includeStdlib"`
-	SelectedModules []string `json:"selectedModules"`
-	ManualModules   []string `json:"manualModules"`
-	OutputPath      string   `json:"outputPath"`
-	LastProjectRoot string   `json:"lastProjectRoot"`
+	IncludeProject  bool     `json:"includeProject" yaml:"includeProject"`
+	IncludeStdlib   bool     `json:"includeStdlib" yaml:"includeStdlib"`
+	SelectedModules []string `json:"selectedModules" yaml:"selectedModules"`
+	ManualModules   []string `json:"manualModules" yaml:"manualModules"`
+	OutputPath      string   `json:"outputPath" yaml:"outputPath"`
+	LastProjectRoot string   `json:"lastProjectRoot" yaml:"lastProjectRoot"`
+	// DocPolicy toggles doc comment inclusion per chunk kind; unset kinds keep docs.
+	DocPolicy map[string]bool `json:"docPolicy,omitempty" yaml:"docPolicy,omitempty"`
+	// InlineDoc keeps doc comments inside the code snippet verbatim.
+	InlineDoc bool `json:"inlineDoc,omitempty" yaml:"inlineDoc,omitempty"`
+	// IncludeImports prepends the imports each function/type chunk references.
+	IncludeImports bool `json:"includeImports,omitempty" yaml:"includeImports,omitempty"`
+	// SignatureOnly emits "signature" chunks with a function's doc and signature instead of its full source.
+	SignatureOnly bool `json:"signatureOnly,omitempty" yaml:"signatureOnly,omitempty"`
+	// FieldChunks emits a chunk per struct field, with struct tags in metadata.
+	FieldChunks bool `json:"fieldChunks,omitempty" yaml:"fieldChunks,omitempty"`
+	// SkipDeprecatedPackages drops packages marked deprecated in their package doc.
+	SkipDeprecatedPackages bool `json:"skipDeprecatedPackages,omitempty" yaml:"skipDeprecatedPackages,omitempty"`
+	// StableIDs derives chunk IDs from import path and symbol instead of file path.
+	StableIDs bool `json:"stableIds,omitempty" yaml:"stableIds,omitempty"`
+	// MaxTokens splits declaration bodies longer than this; zero disables splitting.
+	MaxTokens int `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	// ChunkOverlap is how many tokens consecutive split parts share.
+	ChunkOverlap int `json:"chunkOverlap,omitempty" yaml:"chunkOverlap,omitempty"`
+	// ExportedOnly skips unexported functions, types, values and fields.
+	ExportedOnly bool `json:"exportedOnly,omitempty" yaml:"exportedOnly,omitempty"`
+	// SymbolFilter is a regular expression declarations' names must match.
+	SymbolFilter string `json:"symbolFilter,omitempty" yaml:"symbolFilter,omitempty"`
+	// SymbolFilterIgnoreCase matches SymbolFilter case-insensitively.
+	SymbolFilterIgnoreCase bool `json:"symbolFilterIgnoreCase,omitempty" yaml:"symbolFilterIgnoreCase,omitempty"`
+	// IncludeTests chunks _test.go files too.
+	IncludeTests bool `json:"includeTests,omitempty" yaml:"includeTests,omitempty"`
+	// TestPackages limits included tests to "internal" (package foo) or "external" (package foo_test).
+	TestPackages string `json:"testPackages,omitempty" yaml:"testPackages,omitempty"`
+	// RequireDoc skips functions, types and values that have no doc comment.
+	RequireDoc bool `json:"requireDoc,omitempty" yaml:"requireDoc,omitempty"`
+	// OnlySymbols restricts chunks to these exact names (Foo, Type.Method, Type.Field).
+	OnlySymbols []string `json:"onlySymbols,omitempty" yaml:"onlySymbols,omitempty"`
+	// ExcludeStdlib drops stdlib packages by import path prefix ("internal/" matches at any depth); empty keeps them all.
+	ExcludeStdlib []string `json:"excludeStdlib,omitempty" yaml:"excludeStdlib,omitempty"`
+	// MinStdlibExports drops undocumented stdlib packages with fewer exported declarations; zero keeps them.
+	MinStdlibExports int `json:"minStdlibExports,omitempty" yaml:"minStdlibExports,omitempty"`
+	// StdlibGroups adds curated stdlib topic groups (net, crypto, ...) whether or not they are imported.
+	StdlibGroups []string `json:"stdlibGroups,omitempty" yaml:"stdlibGroups,omitempty"`
+	// SelectedPackages narrows a selected module to the listed import paths.
+	SelectedPackages map[string][]string `json:"selectedPackages,omitempty" yaml:"selectedPackages,omitempty"`
+	// ManualScanDepth caps how many directories below its root a manual module is scanned; zero is unlimited.
+	ManualScanDepth int `json:"manualScanDepth,omitempty" yaml:"manualScanDepth,omitempty"`
+	// Format is the output format: "jsonl" (default), "llamaindex" or "csv".
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Sort is the chunk order: "path" (default) or "source".
+	Sort string `json:"sort,omitempty" yaml:"sort,omitempty"`
+	// PathStyle is how chunk paths are written: "module-relative" (default), "import-path" or "repo-relative".
+	PathStyle string `json:"pathStyle,omitempty" yaml:"pathStyle,omitempty"`
+	// Stream chunks project packages while dependency discovery is still running.
+	Stream bool `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// SkipErrors logs and skips files that fail to parse instead of aborting.
+	SkipErrors bool `json:"skipErrors,omitempty" yaml:"skipErrors,omitempty"`
+	// IDTemplate replaces the built-in chunk ID formats, e.g. "{importPath}/{kind}/{symbol}"; empty keeps them.
+	IDTemplate string `json:"idTemplate,omitempty" yaml:"idTemplate,omitempty"`
+	// RepoURLTemplate builds Metadata.SourceURL for project chunks; {commit} is the git HEAD.
+	RepoURLTemplate string `json:"repoUrlTemplate,omitempty" yaml:"repoUrlTemplate,omitempty"`
+	// SourceURLTemplates builds SourceURL for other source kinds ("third-party", "stdlib").
+	SourceURLTemplates map[string]string `json:"sourceUrlTemplates,omitempty" yaml:"sourceUrlTemplates,omitempty"`
+	// SearchKeywords adds the symbol name split into words ("serve http") to chunk metadata.
+	SearchKeywords bool `json:"searchKeywords,omitempty" yaml:"searchKeywords,omitempty"`
+	// TopicMap is a JSON file of {"pattern", "topic"} rules tagging chunks by symbol name.
+	TopicMap string `json:"topicMap,omitempty" yaml:"topicMap,omitempty"`
+	// MethodTypeContext prefixes method chunks with the first line of their receiver type's doc.
+	MethodTypeContext bool `json:"methodTypeContext,omitempty" yaml:"methodTypeContext,omitempty"`
+	// Embed names the embedding provider ("openai" or "local") that adds a vector to each chunk; empty writes text only.
+	Embed string `json:"embed,omitempty" yaml:"embed,omitempty"`
+	// EmbedModel overrides the provider's default embedding model.
+	EmbedModel string `json:"embedModel,omitempty" yaml:"embedModel,omitempty"`
+	// EmbedURL overrides the provider's default endpoint.
+	EmbedURL string `json:"embedUrl,omitempty" yaml:"embedUrl,omitempty"`
+	// EmbedBatchSize is how many chunks are sent per embedding request.
+	EmbedBatchSize int `json:"embedBatchSize,omitempty" yaml:"embedBatchSize,omitempty"`
+	// Redact lists regular expressions whose matches in chunk text are replaced before output.
+	Redact []RedactRule `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// ModuleOverrides maps a module path ("std" for the stdlib) to settings that override the global ones for its packages.
+	ModuleOverrides map[string]ModuleOverride `json:"moduleOverrides,omitempty" yaml:"moduleOverrides,omitempty"`
+	// RewriteImportPaths maps an import path prefix to the prefix shown in chunk metadata.
+	RewriteImportPaths map[string]string `json:"rewriteImportPaths,omitempty" yaml:"rewriteImportPaths,omitempty"`
+	// CoverProfile is a go test -coverprofile file used to mark function chunks as covered.
+	CoverProfile string `json:"coverProfile,omitempty" yaml:"coverProfile,omitempty"`
+	// Kinds restricts chunks to these kinds (function, type, const, var, field, file-doc, package-doc, directive, usage, module-info, signature, type-bundle, native-code, command); empty means all.
+	Kinds []string `json:"kinds,omitempty" yaml:"kinds,omitempty"`
+	// UsageExamples emits a "usage" chunk with up to this many call snippets per exported project function; zero disables it.
+	UsageExamples int `json:"usageExamples,omitempty" yaml:"usageExamples,omitempty"`
+	// ModuleInfo emits a chunk per selected module summarizing its go.mod.
+	ModuleInfo bool `json:"moduleInfo,omitempty" yaml:"moduleInfo,omitempty"`
+	// AttachExamples appends matching ExampleT_M test functions to method chunks.
+	AttachExamples bool `json:"attachExamples,omitempty" yaml:"attachExamples,omitempty"`
+	// AlwaysEmitPackageDoc keeps a package's package-doc chunk when filters drop all its other chunks; defaults to true.
+	AlwaysEmitPackageDoc bool `json:"alwaysEmitPackageDoc" yaml:"alwaysEmitPackageDoc"`
+	// SkipFilePatterns are file name globs (e.g. "*.gen.go", "zz_generated.*") skipped as generated code, added to the defaults.
+	SkipFilePatterns []string `json:"skipFilePatterns,omitempty" yaml:"skipFilePatterns,omitempty"`
+	// ReplaceSkipPatterns makes SkipFilePatterns replace the default patterns instead of extending them.
+	ReplaceSkipPatterns bool `json:"replaceSkipPatterns,omitempty" yaml:"replaceSkipPatterns,omitempty"`
+	// SkipGenerated skips files with a "// Code generated ... DO NOT EDIT." header.
+	SkipGenerated bool `json:"skipGenerated,omitempty" yaml:"skipGenerated,omitempty"`
+	// TypeBundles emits a chunk per exported type with its declaration and all its method signatures.
+	TypeBundles bool `json:"typeBundles,omitempty" yaml:"typeBundles,omitempty"`
+	// TypeBundleMaxBytes bounds type-bundle chunks; zero uses the built-in limit.
+	TypeBundleMaxBytes int `json:"typeBundleMaxBytes,omitempty" yaml:"typeBundleMaxBytes,omitempty"`
+	// WithTypes type-checks the project to record the interfaces each type implements.
+	WithTypes bool `json:"withTypes,omitempty" yaml:"withTypes,omitempty"`
+	// CommandSummaries emits a chunk per main package listing its flags and subcommands.
+	CommandSummaries bool `json:"commandSummaries,omitempty" yaml:"commandSummaries,omitempty"`
+	// NativeCodeNotes emits a chunk per package that uses cgo or has assembly or C sources.
+	NativeCodeNotes bool `json:"nativeCodeNotes,omitempty" yaml:"nativeCodeNotes,omitempty"`
+	// Directives emits a chunk per file with its //go:generate and other tool directives.
+	Directives bool `json:"directives,omitempty" yaml:"directives,omitempty"`
+	// MaxFileBytes skips source files larger than this, with a warning; zero disables the guard.
+	MaxFileBytes int64 `json:"maxFileBytes,omitempty" yaml:"maxFileBytes,omitempty"`
+	// GoListRetries retries go list module and dependency listings this many times on network or proxy errors.
+	GoListRetries int `json:"goListRetries" yaml:"goListRetries"`
+	// MaxPackages aborts a build that selects more packages than this unless --force is given; zero disables the guard.
+	MaxPackages int `json:"maxPackages,omitempty" yaml:"maxPackages,omitempty"`
+	// MaxTOCBytes shards package symbol indexes larger than this; zero disables sharding.
+	MaxTOCBytes int `json:"maxTocBytes,omitempty" yaml:"maxTocBytes,omitempty"`
+}
+
+// ModuleOverride holds per-module values for exportedOnly, includeTests and
+// requireDoc; a field left out keeps the global value.
+type ModuleOverride struct {
+	ExportedOnly *bool `json:"exportedOnly,omitempty" yaml:"exportedOnly,omitempty"`
+	IncludeTests *bool `json:"includeTests,omitempty" yaml:"includeTests,omitempty"`
+	RequireDoc   *bool `json:"requireDoc,omitempty" yaml:"requireDoc,omitempty"`
+}
+
+// RedactRule replaces matches of Pattern in chunk text with Replacement
+// (default "[REDACTED]").
+type RedactRule struct {
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+// candidateFiles are the project config names Find looks for, in order.
+var candidateFiles = []string{DefaultFile, ".go-rag-pack.yaml", ".go-rag-pack.yml"}
+
+// Find returns the path of the first project config present in root,
+// trying DefaultFile and then its .yaml and .yml variants. When none exists
+// it returns DefaultFile under root so new configs stay JSON.
+func Find(root string) string {
+	for _, name := range candidateFiles {
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(root, DefaultFile)
+}
+
+// FindUp looks for a project config in start and then in each parent
+// directory, like git looks for .git, and returns the directory holding it.
+// The search stops after the first directory with a go.mod, since a config
+// never lives above its module, and at the filesystem root; ok is false when
+// no config was found.
+func FindUp(start string) (dir string, ok bool) {
+	dir = start
+	for {
+		for _, name := range candidateFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, true
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// isYAML reports whether path should be read and written as YAML.
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// unmarshal decodes data into cfg as YAML or JSON depending on path's
+// extension. When strict, unknown keys are an error naming the key, with a
+// suggestion when it differs from a known key only in case.
+func unmarshal(path string, data []byte, cfg *Config, strict bool) error {
+	if !strict {
+		if isYAML(path) {
+			return yaml.Unmarshal(data, cfg)
+		}
+		return json.Unmarshal(data, cfg)
+	}
+
+	var err error
+	if isYAML(path) {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(cfg); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	}
+	if err == nil {
+		return nil
+	}
+	if m := unknownField.FindStringSubmatch(err.Error()); m != nil {
+		name := m[1] + m[2]
+		if known := knownField(name); known != "" {
+			return fmt.Errorf("unknown field %q (did you mean %q?)", name, known)
+		}
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return err
+}
+
+// unknownField matches the unknown-key errors of encoding/json and yaml.v3.
+var unknownField = regexp.MustCompile(`unknown field "([^"]+)"|field (\S+) not found in type`)
+
+// knownField returns the config key equal to name ignoring case, or "".
+func knownField(name string) string {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return ""
 }
 
-// Load reads configuration from the provided path. If the file does not exist,
+// Accepted values of the enum-like fields checked by Validate.
+var (
+	validFormats      = []string{"", "jsonl", "llamaindex", "csv"}
+	validSorts        = []string{"", "path", "source"}
+	validPathStyles   = []string{"", "module-relative", "import-path", "repo-relative"}
+	validTestPackages = []string{"", "internal", "external"}
+	validEmbeds       = []string{"", "openai", "local"}
+	validSourceKinds  = []string{"project", "third-party", "stdlib"}
+)
+
+// Validate checks the enum-like fields and returns an error naming the first
+// field with a value this version does not know.
+func (c Config) Validate() error {
+	for _, check := range []struct {
+		field, value string
+		valid        []string
+	}{
+		{"format", c.Format, validFormats},
+		{"sort", c.Sort, validSorts},
+		{"pathStyle", c.PathStyle, validPathStyles},
+		{"testPackages", c.TestPackages, validTestPackages},
+		{"embed", c.Embed, validEmbeds},
+	} {
+		if !slices.Contains(check.valid, check.value) {
+			return fmt.Errorf("%s: unknown value %q (want one of %s)", check.field, check.value, strings.Join(check.valid[1:], ", "))
+		}
+	}
+	for kind := range c.SourceURLTemplates {
+		if !slices.Contains(validSourceKinds, kind) {
+			return fmt.Errorf("sourceUrlTemplates: unknown source kind %q (want one of %s)", kind, strings.Join(validSourceKinds, ", "))
+		}
+	}
+	return nil
+}
+
+// Load reads configuration from the provided path, as YAML when it ends in
+// .yaml or .yml and as JSON otherwise. If the file does not exist,
 // an empty config and os.ErrNotExist are returned to allow callers to initialise defaults.
 func Load(path string) (Config, error) {
 	var cfg Config
@@ -35,20 +317,155 @@ func Load(path string) (Config, error) {
 		return cfg, err
 	}
 
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return cfg, err
+	if err := unmarshal(path, data, &cfg, true); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
 	}
 
 	return cfg, nil
 }
 
+// LoadLayered builds the effective configuration for a project rooted at root.
+// Layers are applied lowest precedence first, each overriding only the fields
+// it sets: built-in defaults, the global file (see GlobalPath), the repo file
+// at path, and finally the local override next to it (see LocalPath).
+// Missing files are skipped; flags are expected to be applied by the caller.
+// When strict, unknown keys and unknown enum values are errors; otherwise
+// they are ignored, for configs written by a newer version.
+func LoadLayered(root, path string, strict bool) (Config, error) {
+	cfg := Default(root)
+
+	var layers []string
+	if global, err := GlobalPath(); err == nil {
+		layers = append(layers, global)
+	}
+	layers = append(layers, path, LocalPath(path))
+
+	for _, layer := range layers {
+		if err := apply(&cfg, layer, strict); err != nil {
+			return Config{}, err
+		}
+	}
+	if strict {
+		if err := cfg.Validate(); err != nil {
+			return Config{}, fmt.Errorf("config: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadRepo returns the repo file at path over the built-in defaults, without
+// the global and local layers or environment overrides. Commands that rewrite
+// the repo file, like select, start from it so personal settings never end
+// up in the committed config. A missing file gives the defaults.
+func LoadRepo(root, path string, strict bool) (Config, error) {
+	cfg := Default(root)
+	if err := apply(&cfg, path, strict); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// GlobalPath returns the machine-wide config location,
+// $XDG_CONFIG_HOME/go-rag-pack/config.json or ~/.config/go-rag-pack/config.json.
+func GlobalPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "go-rag-pack", GlobalFile), nil
+}
+
+// LocalPath returns the personal, uncommitted override for the repo config at
+// path, e.g. .go-rag-pack.local.json next to .go-rag-pack.json.
+func LocalPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+// apply overlays the fields present in the file at path onto cfg. A missing
+// file leaves cfg untouched.
+func apply(cfg *Config, path string, strict bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := unmarshal(path, data, cfg, strict); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Environment variables read by ApplyEnv.
+const (
+	EnvOutput         = "GO_RAG_PACK_OUTPUT"
+	EnvIncludeProject = "GO_RAG_PACK_INCLUDE_PROJECT"
+	EnvIncludeStdlib  = "GO_RAG_PACK_INCLUDE_STDLIB"
+	EnvModules        = "GO_RAG_PACK_MODULES"
+)
+
+// ApplyEnv overrides cfg with any GO_RAG_PACK_* environment variables that are
+// set. Booleans accept 1/true/yes and 0/false/no; unrecognised values are
+// ignored. GO_RAG_PACK_MODULES is a comma-separated list replacing the
+// selected modules. The result is the effective config for a build and is
+// never saved; see LoadRepo.
+func ApplyEnv(cfg Config) Config {
+	if v, ok := os.LookupEnv(EnvOutput); ok && v != "" {
+		cfg.OutputPath = v
+	}
+	if b, ok := envBool(EnvIncludeProject); ok {
+		cfg.IncludeProject = b
+	}
+	if b, ok := envBool(EnvIncludeStdlib); ok {
+		cfg.IncludeStdlib = b
+	}
+	if v, ok := os.LookupEnv(EnvModules); ok {
+		var modules []string
+		for _, part := range strings.Split(v, ",") {
+			if name := strings.TrimSpace(part); name != "" {
+				modules = append(modules, name)
+			}
+		}
+		cfg.SelectedModules = modules
+	}
+	return cfg
+}
+
+func envBool(key string) (bool, bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes":
+		return true, true
+	case "0", "false", "no":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
 // Save writes the configuration to disk, creating parent directories as needed.
+// Like Load, it picks YAML or JSON from path's extension.
 func Save(path string, cfg Config) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(cfg)
+	} else {
+		data, err = json.MarshalIndent(cfg, "", "  ")
+	}
 	if err != nil {
 		return err
 	}
@@ -59,11 +476,14 @@ func Save(path string, cfg Config) error {
 // Default creates a new configuration with sensible defaults for a project rooted at root.
 func Default(root string) Config {
 	return Config{
-		IncludeProject:  true,
-		IncludeStdlib:   false,
-		SelectedModules: nil,
-		ManualModules:   nil,
-		OutputPath:      filepath.Join("rag", "go_docs.jsonl"),
-		LastProjectRoot: root,
+		IncludeProject:       true,
+		IncludeStdlib:        false,
+		SelectedModules:      nil,
+		ManualModules:        nil,
+		OutputPath:           filepath.Join("rag", "go_docs.jsonl"),
+		LastProjectRoot:      root,
+		MaxTOCBytes:          DefaultMaxTOCBytes,
+		AlwaysEmitPackageDoc: true,
+		GoListRetries:        DefaultGoListRetries,
 	}
 }
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFile writes data to path, creating its directory.
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayeredPrecedence(t *testing.T) {
+	tests := []struct {
+		name                string
+		global, repo, local string
+		wantFormat          string
+		wantMaxTokens       int
+	}{
+		{
+			name:       "defaults only",
+			wantFormat: "",
+		},
+		{
+			name:       "global over defaults",
+			global:     `{"format": "csv"}`,
+			wantFormat: "csv",
+		},
+		{
+			name:          "repo over global",
+			global:        `{"format": "csv", "maxTokens": 100}`,
+			repo:          `{"format": "llamaindex"}`,
+			wantFormat:    "llamaindex",
+			wantMaxTokens: 100,
+		},
+		{
+			name:          "local over repo",
+			global:        `{"format": "csv"}`,
+			repo:          `{"format": "llamaindex", "maxTokens": 200}`,
+			local:         `{"format": "jsonl"}`,
+			wantFormat:    "jsonl",
+			wantMaxTokens: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xdg := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", xdg)
+			root := t.TempDir()
+			path := filepath.Join(root, DefaultFile)
+			if tt.global != "" {
+				writeFile(t, filepath.Join(xdg, "go-rag-pack", GlobalFile), tt.global)
+			}
+			if tt.repo != "" {
+				writeFile(t, path, tt.repo)
+			}
+			if tt.local != "" {
+				writeFile(t, LocalPath(path), tt.local)
+			}
+
+			cfg, err := LoadLayered(root, path, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Format != tt.wantFormat {
+				t.Errorf("Format = %q, want %q", cfg.Format, tt.wantFormat)
+			}
+			if cfg.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("MaxTokens = %d, want %d", cfg.MaxTokens, tt.wantMaxTokens)
+			}
+		})
+	}
+}
+
+func TestLoadRepoSkipsGlobalAndLocal(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv(EnvOutput, "/tmp/ci-out.jsonl")
+	root := t.TempDir()
+	path := filepath.Join(root, DefaultFile)
+	writeFile(t, filepath.Join(xdg, "go-rag-pack", GlobalFile), `{"format": "csv", "signatureOnly": true}`)
+	writeFile(t, path, `{"includeStdlib": true}`)
+	writeFile(t, LocalPath(path), `{"maxTokens": 50}`)
+
+	cfg, err := LoadRepo(root, path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Format != "" || cfg.SignatureOnly {
+		t.Errorf("global layer leaked: format %q, signatureOnly %v", cfg.Format, cfg.SignatureOnly)
+	}
+	if cfg.MaxTokens != 0 {
+		t.Errorf("local layer leaked: maxTokens %d", cfg.MaxTokens)
+	}
+	if cfg.OutputPath != Default(root).OutputPath {
+		t.Errorf("OutputPath = %q, want the default", cfg.OutputPath)
+	}
+	if !cfg.IncludeStdlib {
+		t.Error("IncludeStdlib = false, want the repo's true")
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		strict  bool
+		wantErr string
+	}{
+		{name: "known json", file: DefaultFile, data: `{"format": "csv"}`, strict: true},
+		{name: "unknown json", file: DefaultFile, data: `{"fromat": "csv"}`, strict: true, wantErr: `unknown field "fromat"`},
+		{name: "case typo yaml", file: ".go-rag-pack.yaml", data: "maxtokens: 5\n", strict: true, wantErr: `did you mean "maxTokens"?`},
+		{name: "unknown yaml", file: ".go-rag-pack.yaml", data: "fromat: csv\n", strict: true, wantErr: `unknown field "fromat"`},
+		{name: "unknown ignored when lax", file: DefaultFile, data: `{"fromat": "csv"}`, strict: false},
+		{name: "bad enum", file: DefaultFile, data: `{"format": "xml"}`, strict: true, wantErr: `format: unknown value "xml"`},
+		{name: "bad enum ignored when lax", file: DefaultFile, data: `{"format": "xml"}`, strict: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			root := t.TempDir()
+			path := filepath.Join(root, tt.file)
+			writeFile(t, path, tt.data)
+
+			_, err := LoadLayered(root, path, tt.strict)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("no error, want one containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	base := Config{OutputPath: "rag/go_docs.jsonl", IncludeProject: true, SelectedModules: []string{"example.com/a"}}
+	tests := []struct {
+		name string
+		env  map[string]string
+		want func(Config) Config
+	}{
+		{
+			name: "unset leaves config",
+			want: func(c Config) Config { return c },
+		},
+		{
+			name: "output",
+			env:  map[string]string{EnvOutput: "/tmp/ci-out.jsonl"},
+			want: func(c Config) Config { c.OutputPath = "/tmp/ci-out.jsonl"; return c },
+		},
+		{
+			name: "empty output ignored",
+			env:  map[string]string{EnvOutput: ""},
+			want: func(c Config) Config { return c },
+		},
+		{
+			name: "booleans yes and 0",
+			env:  map[string]string{EnvIncludeStdlib: "yes", EnvIncludeProject: "0"},
+			want: func(c Config) Config { c.IncludeStdlib = true; c.IncludeProject = false; return c },
+		},
+		{
+			name: "booleans TRUE and no",
+			env:  map[string]string{EnvIncludeStdlib: "TRUE", EnvIncludeProject: "no"},
+			want: func(c Config) Config { c.IncludeStdlib = true; c.IncludeProject = false; return c },
+		},
+		{
+			name: "unrecognised boolean ignored",
+			env:  map[string]string{EnvIncludeProject: "maybe"},
+			want: func(c Config) Config { return c },
+		},
+		{
+			name: "modules replace selection",
+			env:  map[string]string{EnvModules: " example.com/b, ,example.com/c "},
+			want: func(c Config) Config { c.SelectedModules = []string{"example.com/b", "example.com/c"}; return c },
+		},
+		{
+			name: "empty modules clear selection",
+			env:  map[string]string{EnvModules: ""},
+			want: func(c Config) Config { c.SelectedModules = nil; return c },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{EnvOutput, EnvIncludeProject, EnvIncludeStdlib, EnvModules} {
+				if v, ok := tt.env[key]; ok {
+					t.Setenv(key, v)
+				} else {
+					t.Setenv(key, "")
+					os.Unsetenv(key)
+				}
+			}
+			got := ApplyEnv(base)
+			want := tt.want(base)
+			if got.OutputPath != want.OutputPath || got.IncludeProject != want.IncludeProject || got.IncludeStdlib != want.IncludeStdlib || !slices.Equal(got.SelectedModules, want.SelectedModules) {
+				t.Errorf("ApplyEnv = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package discover
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultCacheDir returns where DiscoverCached keeps go list output by default.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-rag-pack", "discover"), nil
+}
+
+// DiscoverCached behaves like Discover but reuses the raw go list output from
+// a previous run when the project's go.mod, go.sum and Go toolchain are
+// unchanged. The cache entry lives under cacheDir; refresh ignores any
+// existing entry and rewrites it. Cache read or write failures fall back to
+// running go list directly. retries is passed on as in Discover.
+func DiscoverCached(root, cacheDir string, refresh bool, retries int) (Project, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return Project{}, err
+	}
+
+	key, err := cacheKey(absRoot)
+	if err != nil {
+		return discover(absRoot, runGoCommand, retries)
+	}
+	c := &listCache{
+		path:    filepath.Join(cacheDir, key+".json"),
+		entries: make(map[string][]byte),
+	}
+	if !refresh {
+		c.load()
+	}
+
+	proj, err := discover(absRoot, c.run, retries)
+	if err != nil {
+		return Project{}, err
+	}
+	if c.dirty {
+		// A failed write only costs the next run a cache miss.
+		_ = c.save()
+	}
+	return proj, nil
+}
+
+// cacheKey hashes everything that can change go list output for root: the
+// module files (including vendor/modules.txt), the toolchain version and
+// flags, and the layout of the project's Go files (new packages or imports
+// change the package lists).
+func cacheKey(root string) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(root))
+	for _, name := range []string{"go.mod", "go.sum", filepath.Join("vendor", "modules.txt")} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		h.Write([]byte{0})
+		h.Write(data)
+	}
+	env, err := runGoCommand(context.Background(), root, "env", "GOVERSION", "GOFLAGS", "GOOS", "GOARCH")
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte{0})
+	h.Write(env)
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "\x00%s %d %d", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// listCache memoises go command output by argument list.
+type listCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string][]byte
+	dirty   bool
+}
+
+func (c *listCache) run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	key := strings.Join(args, " ")
+	c.mu.Lock()
+	out, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return out, nil
+	}
+
+	out, err := runGoCommand(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = out
+	c.dirty = true
+	c.mu.Unlock()
+	return out, nil
+}
+
+func (c *listCache) load() {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var entries map[string][]byte
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	c.entries = entries
+}
+
+// save writes the cache through a temp file and rename so concurrent runs
+// never observe a partially written entry.
+func (c *listCache) save() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,74 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheKeyInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, root string)
+		same   bool
+	}{
+		{name: "nothing changed", change: func(*testing.T, string) {}, same: true},
+		{name: "go.mod edited", change: func(t *testing.T, root string) {
+			appendFile(t, filepath.Join(root, "go.mod"), "\nrequire example.com/other v1.0.0\n")
+		}},
+		{name: "modules.txt edited", change: func(t *testing.T, root string) {
+			appendFile(t, filepath.Join(root, "vendor", "modules.txt"), "# example.com/other v1.0.0\n")
+		}},
+		{name: "go.sum added", change: func(t *testing.T, root string) {
+			appendFile(t, filepath.Join(root, "go.sum"), "example.com/dep v1.2.0 h1:x\n")
+		}},
+		{name: "package added", change: func(t *testing.T, root string) {
+			if err := os.MkdirAll(filepath.Join(root, "newpkg"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			appendFile(t, filepath.Join(root, "newpkg", "new.go"), "package newpkg\n")
+		}},
+		{name: "go file grown", change: func(t *testing.T, root string) {
+			appendFile(t, filepath.Join(root, "main.go"), "\nimport _ \"fmt\"\n")
+		}},
+		{name: "testdata ignored", change: func(t *testing.T, root string) {
+			if err := os.MkdirAll(filepath.Join(root, "testdata"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			appendFile(t, filepath.Join(root, "testdata", "x.go"), "package x\n")
+		}, same: true},
+		{name: "non-Go file ignored", change: func(t *testing.T, root string) {
+			appendFile(t, filepath.Join(root, "README.md"), "# m\n")
+		}, same: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := vendorFixture(t)
+			before, err := cacheKey(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(t, root)
+			after, err := cacheKey(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (before == after) != tt.same {
+				t.Errorf("key changed = %v, want %v", before != after, !tt.same)
+			}
+		})
+	}
+}
+
+// appendFile appends data to path, creating it if needed.
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,14 +2,18 @@ package discover
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // Module represents a Go module known to the project.
@@ -31,12 +35,38 @@ type Package struct {
 	Module     *Module
 	Standard   bool `json:"Standard"`
 	DepOnly    bool `json:"DepOnly"`
+	// Imports, TestImports and XTestImports are the import paths of the
+	// package's files, its in-package tests and its external tests.
+	Imports      []string `json:"Imports"`
+	TestImports  []string `json:"TestImports"`
+	XTestImports []string `json:"XTestImports"`
+	// Error is set by go list -e on a package that cannot be loaded, such
+	// as one whose files are all excluded by build constraints.
+	Error *PackageError `json:"Error"`
+}
+
+// PackageError is the error go list -e reports for a package.
+type PackageError struct {
+	Err string `json:"Err"`
 }
 
+// Module relations reported in ModuleUsage.Relation.
+const (
+	// RelationDirect modules are imported by the main module's non-test code.
+	RelationDirect = "direct"
+	// RelationIndirect modules are only reached through other dependencies.
+	RelationIndirect = "indirect"
+	// RelationTest modules are imported only by the main module's tests.
+	RelationTest = "test"
+)
+
 // ModuleUsage ties a module to the packages the project imports from it.
 type ModuleUsage struct {
 	Module   Module
 	Packages []Package
+	// Relation says why the module is in the project: RelationDirect,
+	// RelationIndirect or RelationTest.
+	Relation string
 }
 
 // Project summarises the Go project located at Root.
@@ -47,18 +77,49 @@ type Project struct {
 	ThirdParty       []ModuleUsage
 	StdlibPackages   []Package
 	AllModules       []Module
+	// Skipped are the dependency packages left out because go list could
+	// not load them; see Package.Error.
+	Skipped []Package
 }
 
 // Discover inspects the repository rooted at root and gathers details about
-// its modules, packages, and dependencies.
-func Discover(root string) (Project, error) {
+// its modules, packages, and dependencies. The module and dependency
+// listings, which may reach the module proxy, are retried up to retries
+// times on network or proxy errors.
+func Discover(root string, retries int) (Project, error) {
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return Project{}, err
 	}
+	return discover(absRoot, runGoCommand, retries)
+}
 
-	modules, err := goListModules(absRoot)
-	if err != nil {
+// goRunner executes a go subcommand in dir and returns its stdout. The
+// command is killed when ctx is cancelled.
+type goRunner func(ctx context.Context, dir string, args ...string) ([]byte, error)
+
+// discover runs the module, package and dependency listings concurrently.
+// The first failure cancels the other go list processes and is the error
+// returned, so a cancellation never masks the real cause. Only the listings
+// that can hit the network are retried; go list ./... reads local files.
+func discover(absRoot string, run goRunner, retries int) (Project, error) {
+	remote := withRetries(run, retries)
+	var modules []Module
+	var internalPkgs, depPkgs []Package
+	g, ctx := errgroup.WithContext(context.Background())
+	g.Go(func() (err error) {
+		modules, err = goListModules(ctx, absRoot, remote)
+		return err
+	})
+	g.Go(func() (err error) {
+		internalPkgs, err = goListPackages(ctx, absRoot, "./...", run)
+		return err
+	})
+	g.Go(func() (err error) {
+		depPkgs, err = goListDeps(ctx, absRoot, remote)
+		return err
+	})
+	if err := g.Wait(); err != nil {
 		return Project{}, err
 	}
 	if len(modules) == 0 {
@@ -77,19 +138,20 @@ func Discover(root string) (Project, error) {
 		return Project{}, errors.New("main module not identified in go list output")
 	}
 
-	internalPkgs, err := goListPackages(absRoot, "./...")
-	if err != nil {
-		return Project{}, err
-	}
 	internalPkgs = filterPackagesByModule(internalPkgs, mainModule.Path)
-
-	depPkgs, err := goListDeps(absRoot)
-	if err != nil {
-		return Project{}, err
+	var skipped []Package
+	loaded := depPkgs[:0]
+	for _, p := range depPkgs {
+		if p.Error != nil {
+			skipped = append(skipped, p)
+			continue
+		}
+		loaded = append(loaded, p)
 	}
-
+	depPkgs = loaded
 	stdlib := collectStdlib(depPkgs)
 	thirdParty := collectThirdParty(depPkgs, moduleByPath, mainModule.Path)
+	thirdParty = classifyModules(thirdParty, internalPkgs, modules)
 
 	return Project{
 		Root:             absRoot,
@@ -98,6 +160,7 @@ func Discover(root string) (Project, error) {
 		ThirdParty:       thirdParty,
 		StdlibPackages:   stdlib,
 		AllModules:       modules,
+		Skipped:          skipped,
 	}, nil
 }
 
@@ -147,6 +210,11 @@ func collectThirdParty(depPkgs []Package, moduleByPath map[string]Module, mainPa
 				mod = known
 			}
 		}
+		// Vendored packages report a module without a directory; the module
+		// root is found from the package's place under vendor/.
+		if mod.Dir == "" {
+			mod.Dir = vendorModuleDir(p.Dir, p.ImportPath, mod.Path)
+		}
 
 		ent, ok := third[mod.Path]
 		if !ok {
@@ -184,9 +252,86 @@ func collectThirdParty(depPkgs []Package, moduleByPath map[string]Module, mainPa
 	return result
 }
 
-func goListModules(dir string) ([]Module, error) {
-	output, err := runGoCommand(dir, "list", "-m", "-json", "all")
+// classifyModules sets each module's Relation from the imports of the main
+// module's packages. Modules imported only by tests are not in the -deps
+// listing, so they are added here with their test-imported packages, rooted
+// in the module's directory.
+func classifyModules(thirdParty []ModuleUsage, internalPkgs []Package, modules []Module) []ModuleUsage {
+	direct := make(map[string]bool)
+	testOnly := make(map[string][]string)
+	for _, p := range internalPkgs {
+		for _, imp := range p.Imports {
+			if mod := owningModule(imp, modules); mod != nil {
+				direct[mod.Path] = true
+			}
+		}
+		for _, imp := range append(slices.Clone(p.TestImports), p.XTestImports...) {
+			if mod := owningModule(imp, modules); mod != nil && !slices.Contains(testOnly[mod.Path], imp) {
+				testOnly[mod.Path] = append(testOnly[mod.Path], imp)
+			}
+		}
+	}
+
+	listed := make(map[string]bool, len(thirdParty))
+	for i := range thirdParty {
+		path := thirdParty[i].Module.Path
+		listed[path] = true
+		if direct[path] {
+			thirdParty[i].Relation = RelationDirect
+		} else {
+			thirdParty[i].Relation = RelationIndirect
+		}
+	}
+
+	for _, mod := range modules {
+		imports, ok := testOnly[mod.Path]
+		if !ok || listed[mod.Path] || mod.Main {
+			continue
+		}
+		sort.Strings(imports)
+		pkgs := make([]Package, 0, len(imports))
+		for _, imp := range imports {
+			pkg := Package{ImportPath: imp, Module: &mod}
+			if mod.Dir != "" {
+				pkg.Dir = filepath.Join(mod.Dir, filepath.FromSlash(strings.TrimPrefix(imp, mod.Path)))
+			}
+			pkgs = append(pkgs, pkg)
+		}
+		thirdParty = append(thirdParty, ModuleUsage{Module: mod, Packages: pkgs, Relation: RelationTest})
+	}
+	sort.Slice(thirdParty, func(i, j int) bool {
+		return thirdParty[i].Module.Path < thirdParty[j].Module.Path
+	})
+	return thirdParty
+}
+
+// owningModule returns the non-main module whose path is the longest prefix
+// of importPath, or nil for stdlib and main-module packages.
+func owningModule(importPath string, modules []Module) *Module {
+	var best *Module
+	for i, mod := range modules {
+		if importPath != mod.Path && !strings.HasPrefix(importPath, mod.Path+"/") {
+			continue
+		}
+		if best == nil || len(mod.Path) > len(best.Path) {
+			best = &modules[i]
+		}
+	}
+	if best == nil || best.Main {
+		return nil
+	}
+	return best
+}
+
+func goListModules(ctx context.Context, dir string, run goRunner) ([]Module, error) {
+	output, err := run(ctx, dir, "list", "-m", "-json", "all")
 	if err != nil {
+		// In vendor mode go list -m all fails, but modules.txt has the answer.
+		if strings.Contains(err.Error(), "using the vendor directory") {
+			if modules, verr := vendorModules(ctx, dir, run); verr == nil {
+				return modules, nil
+			}
+		}
 		return nil, err
 	}
 
@@ -209,8 +354,32 @@ func goListModules(dir string) ([]Module, error) {
 	return modules, nil
 }
 
-func goListPackages(dir string, pattern string) ([]Package, error) {
-	output, err := runGoCommand(dir, "list", "-json", pattern)
+func goListPackages(ctx context.Context, dir string, pattern string, run goRunner) ([]Package, error) {
+	output, err := run(ctx, dir, "list", "-json", pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var p Package
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// goListDeps lists the project's packages and everything they import. It
+// runs in go list's error-tolerant mode, so a dependency that fails to load
+// is reported through Package.Error instead of aborting discovery.
+func goListDeps(ctx context.Context, dir string, run goRunner) ([]Package, error) {
+	output, err := run(ctx, dir, "list", "-e", "-deps", "-json", "./...")
 	if err != nil {
 		return nil, err
 	}
@@ -230,8 +399,82 @@ func goListPackages(dir string, pattern string) ([]Package, error) {
 	return pkgs, nil
 }
 
-func goListDeps(dir string) ([]Package, error) {
-	output, err := runGoCommand(dir, "list", "-deps", "-json", "./...")
+// StreamPackages runs go list on the packages of the main module rooted at
+// root and sends each one to out as soon as it is decoded, so callers can
+// start work before listing finishes. It returns once go list exits and does
+// not close out.
+func StreamPackages(root string, out chan<- Package) error {
+	cmd := exec.Command("go", "list", "-json", "./...")
+	cmd.Dir = root
+	cmd.Env = GoEnv()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(stdout)
+	var decodeErr error
+	for {
+		var p Package
+		if err := dec.Decode(&p); err != nil {
+			if !errors.Is(err, io.EOF) {
+				decodeErr = err
+			}
+			break
+		}
+		if p.Module != nil && p.Module.Main {
+			out <- p
+		}
+	}
+	// Drain whatever is left so go list is not blocked writing to the pipe.
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("go list -json ./...: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return decodeErr
+}
+
+// DownloadModule runs go mod download for path at version (or the version
+// the module graph selects, when empty) from root, populating the module
+// cache, and returns the module with its Dir and GoMod set.
+func DownloadModule(root, path, version string) (Module, error) {
+	query := path
+	if version != "" {
+		query += "@" + version
+	}
+	cmd := exec.Command("go", "mod", "download", "-json", query)
+	cmd.Dir = root
+	cmd.Env = GoEnv()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, runErr := cmd.Output()
+	// On failure go mod download still prints the module, with an Error.
+	var m struct {
+		Module
+		Error string
+	}
+	if err := json.Unmarshal(out, &m); err == nil && m.Error != "" {
+		return Module{}, fmt.Errorf("go mod download %s: %s", query, m.Error)
+	}
+	if runErr != nil {
+		return Module{}, fmt.Errorf("go mod download %s: %w (%s)", query, runErr, strings.TrimSpace(stderr.String()))
+	}
+	if m.Dir == "" {
+		return Module{}, fmt.Errorf("go mod download %s: no directory reported", query)
+	}
+	return m.Module, nil
+}
+
+// ResolvePackages looks up importPaths with go list from root, so they may
+// name project, dependency or stdlib packages. An import path that cannot be
+// found in the module graph is an error.
+func ResolvePackages(root string, importPaths []string) ([]Package, error) {
+	output, err := runGoCommand(context.Background(), root, append([]string{"list", "-e", "-json"}, importPaths...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -246,14 +489,20 @@ func goListDeps(dir string) ([]Package, error) {
 			}
 			return nil, err
 		}
+		if p.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", p.ImportPath, p.Error.Err)
+		}
 		pkgs = append(pkgs, p)
 	}
 	return pkgs, nil
 }
 
-func runGoCommand(dir string, args ...string) ([]byte, error) {
-	cmd := exec.Command("go", args...)
+// runGoCommand runs go with args in dir and the environment from GoEnv,
+// returning its standard output.
+func runGoCommand(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
 	cmd.Dir = dir
+	cmd.Env = GoEnv()
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -0,0 +1,22 @@
+package discover
+
+import "os"
+
+// goEnvOverrides are set on every go command over the inherited
+// environment. Module mode is forced on because discovery reads module
+// information that GOPATH mode does not report; GOFLAGS, GOPROXY,
+// GONOSUMCHECK and the rest still come from the user.
+var goEnvOverrides = []string{"GO111MODULE=on"}
+
+// extraGoEnv is appended after goEnvOverrides, so tests can inject
+// variables such as GOFLAGS=-mod=vendor or GOPROXY=off without touching
+// the process environment.
+var extraGoEnv []string
+
+// GoEnv returns the environment go commands run with: os.Environ plus the
+// tool's overrides. Later entries win, as exec.Cmd keeps the last value of
+// a duplicated key.
+func GoEnv() []string {
+	env := append(os.Environ(), goEnvOverrides...)
+	return append(env, extraGoEnv...)
+}
@@ -0,0 +1,102 @@
+package discover
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typesLoadMode loads type information for the project's packages and their
+// dependencies, and the module of each so stdlib packages can be told apart.
+const typesLoadMode = packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps | packages.NeedModule
+
+// Implementations type-checks the packages of the module rooted at root and
+// reports, for each named non-interface type they declare, the non-empty
+// interfaces it or its pointer implements. Interfaces are taken from the
+// project's packages and from the exported interfaces of every importable
+// stdlib package they depend on. Keys are "importPath.TypeName" and
+// interfaces are written like "io.Reader", sorted.
+//
+// Packages that fail to type-check are left out and returned as errors,
+// one per package, so callers can warn and still use the rest. Generic
+// types and interfaces are skipped.
+func Implementations(root string) (map[string][]string, []error, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: typesLoadMode, Dir: root, Env: GoEnv()}, "./...")
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading packages for type information: %w", err)
+	}
+
+	var pkgErrs []error
+	var project []*packages.Package
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			pkgErrs = append(pkgErrs, fmt.Errorf("%s: %v", pkg.PkgPath, pkg.Errors[0]))
+			continue
+		}
+		if pkg.Types == nil {
+			continue
+		}
+		project = append(project, pkg)
+	}
+
+	var ifaces []*types.TypeName
+	seen := make(map[string]bool)
+	addInterfaces := func(pkg *types.Package, exportedOnly bool) {
+		if seen[pkg.Path()] {
+			return
+		}
+		seen[pkg.Path()] = true
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() || exportedOnly && !tn.Exported() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+			if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+				ifaces = append(ifaces, tn)
+			}
+		}
+	}
+	for _, pkg := range project {
+		addInterfaces(pkg.Types, false)
+	}
+	packages.Visit(project, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil && pkg.Module == nil && len(pkg.Errors) == 0 && !isInternalPath(pkg.PkgPath) {
+			addInterfaces(pkg.Types, true)
+		}
+	})
+
+	impls := make(map[string][]string)
+	for _, pkg := range project {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+				continue
+			}
+			ptr := types.NewPointer(named)
+			var names []string
+			for _, iface := range ifaces {
+				it := iface.Type().Underlying().(*types.Interface)
+				if types.Implements(named, it) || types.Implements(ptr, it) {
+					names = append(names, iface.Pkg().Path()+"."+iface.Name())
+				}
+			}
+			if len(names) > 0 {
+				sort.Strings(names)
+				impls[pkg.PkgPath+"."+name] = names
+			}
+		}
+	}
+	return impls, pkgErrs, nil
+}
@@ -0,0 +1,75 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry; each later retry waits
+// twice as long as the one before.
+var retryBackoff = time.Second
+
+// transientMarkers are fragments of go command errors caused by the network
+// or the module proxy rather than by the project, so running the command
+// again may succeed.
+var transientMarkers = []string{
+	"dial tcp",
+	"i/o timeout",
+	"connection reset",
+	"connection refused",
+	"TLS handshake timeout",
+	"no such host",
+	"temporary failure",
+	"unexpected EOF",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// isTransient reports whether err looks like a network or proxy failure. Errors
+// such as a malformed go.mod or a missing package are permanent.
+func isTransient(err error) bool {
+	msg := err.Error()
+	for _, marker := range transientMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetries wraps run so a transient failure is retried up to retries
+// times with exponential backoff. A permanent error, or cancellation of
+// ctx, returns at once. When every attempt fails the error lists each one.
+func withRetries(run goRunner, retries int) goRunner {
+	if retries <= 0 {
+		return run
+	}
+	return func(ctx context.Context, dir string, args ...string) ([]byte, error) {
+		var errs []error
+		wait := retryBackoff
+		for attempt := 0; ; attempt++ {
+			out, err := run(ctx, dir, args...)
+			if err == nil {
+				return out, nil
+			}
+			errs = append(errs, fmt.Errorf("attempt %d: %w", attempt+1, err))
+			if attempt == retries || !isTransient(err) || ctx.Err() != nil {
+				break
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+			wait *= 2
+		}
+		if len(errs) == 1 {
+			return nil, errors.Unwrap(errs[0])
+		}
+		return nil, fmt.Errorf("go %s failed after %d attempts: %w", strings.Join(args, " "), len(errs), errors.Join(errs...))
+	}
+}
@@ -0,0 +1,172 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StdlibGroups maps curated topic names to the stdlib import-path prefixes
+// they cover. A prefix matches the package itself and everything below it.
+var StdlibGroups = map[string][]string{
+	"net":         {"net", "mime", "crypto/tls", "crypto/x509"},
+	"crypto":      {"crypto", "hash"},
+	"encoding":    {"encoding", "compress", "archive"},
+	"concurrency": {"sync", "context"},
+}
+
+// ExpandStdlibGroups resolves group names to their sorted, de-duplicated
+// import-path prefixes. Unknown names are reported as an error.
+func ExpandStdlibGroups(names []string) ([]string, error) {
+	seen := make(map[string]struct{})
+	for _, name := range names {
+		prefixes, ok := StdlibGroups[name]
+		if !ok {
+			known := make([]string, 0, len(StdlibGroups))
+			for group := range StdlibGroups {
+				known = append(known, group)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown stdlib group %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		for _, prefix := range prefixes {
+			seen[prefix] = struct{}{}
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for prefix := range seen {
+		out = append(out, prefix)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// StdlibByPrefix lists the public stdlib packages whose import path falls
+// under one of prefixes, regardless of whether the project imports them.
+func StdlibByPrefix(dir string, prefixes []string) ([]Package, error) {
+	pkgs, err := goListPackages(context.Background(), dir, "std", runGoCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Package
+	for _, p := range pkgs {
+		if isInternalPath(p.ImportPath) {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if HasPathPrefix(p.ImportPath, prefix) {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ImportPath < out[j].ImportPath
+	})
+	return out, nil
+}
+
+// HasPathPrefix reports whether importPath is prefix or a package below it.
+// A prefix ending in "/" matches anything underneath it.
+func HasPathPrefix(importPath, prefix string) bool {
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(importPath, prefix)
+	}
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
+
+// MatchStdlibExclude reports whether importPath matches one of patterns, as
+// used by the excludeStdlib setting. Patterns follow HasPathPrefix, and one
+// ending in "/" also matches those path elements at any depth, so
+// "internal/" covers internal/abi, crypto/internal/boring and log/internal.
+func MatchStdlibExclude(importPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if HasPathPrefix(importPath, pattern) {
+			return true
+		}
+		if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+			if importPath == dir || strings.Contains(importPath, "/"+pattern) || strings.HasSuffix(importPath, "/"+dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isInternalPath reports whether a stdlib import path cannot be imported
+// from outside the standard library, such as internal/poll or
+// vendor/golang.org/x/net.
+func isInternalPath(importPath string) bool {
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "internal" || elem == "vendor" {
+			return true
+		}
+	}
+	return false
+}
+
+// ThinPackage reports whether the package in dir is too slight to be worth
+// indexing: it has no package doc comment and fewer than minExports
+// exported top-level declarations across its non-test files. Unreadable
+// packages are not thin, so they surface as build errors instead of
+// vanishing.
+func ThinPackage(dir string, minExports int) bool {
+	if minExports <= 0 {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	files, exports := 0, 0
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return false
+		}
+		if file.Doc != nil {
+			return false
+		}
+		files++
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil && d.Name.IsExported() {
+					exports++
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch sp := spec.(type) {
+					case *ast.TypeSpec:
+						if sp.Name.IsExported() {
+							exports++
+						}
+					case *ast.ValueSpec:
+						for _, n := range sp.Names {
+							if n.IsExported() {
+								exports++
+							}
+						}
+					}
+				}
+			}
+		}
+		if exports >= minExports {
+			return false
+		}
+	}
+	return files > 0
+}
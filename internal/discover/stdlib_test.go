@@ -0,0 +1,186 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writePackage writes files, keyed by name, into a new directory and
+// returns it.
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestThinPackage(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		minExports int
+		want       bool
+	}{
+		{
+			name:       "thin alias package",
+			files:      map[string]string{"alias.go": "package alias\n\nimport \"io\"\n\ntype Reader = io.Reader\n"},
+			minExports: 3,
+			want:       true,
+		},
+		{
+			name: "substantive package",
+			files: map[string]string{
+				"a.go": "package sub\n\nfunc A() {}\nfunc B() {}\n",
+				"b.go": "package sub\n\ntype T struct{}\n\nconst C = 1\n",
+			},
+			minExports: 3,
+			want:       false,
+		},
+		{
+			name:       "documented package is never thin",
+			files:      map[string]string{"doc.go": "// Package tiny does one thing well.\npackage tiny\n\nfunc Do() {}\n"},
+			minExports: 3,
+			want:       false,
+		},
+		{
+			name:       "methods and unexported names do not count",
+			files:      map[string]string{"m.go": "package m\n\ntype T struct{}\n\nfunc (T) A() {}\nfunc (T) B() {}\nfunc helper() {}\n"},
+			minExports: 2,
+			want:       true,
+		},
+		{
+			name:       "tests do not count",
+			files:      map[string]string{"a.go": "package a\n\nfunc A() {}\n", "a_test.go": "package a\n\nfunc B() {}\nfunc C() {}\n"},
+			minExports: 2,
+			want:       true,
+		},
+		{
+			name:       "zero threshold keeps everything",
+			files:      map[string]string{"alias.go": "package alias\n"},
+			minExports: 0,
+			want:       false,
+		},
+		{
+			name:       "unparsable package is not thin",
+			files:      map[string]string{"bad.go": "package bad\n\nfunc (\n"},
+			minExports: 3,
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, tt.files)
+			if got := ThinPackage(dir, tt.minExports); got != tt.want {
+				t.Errorf("ThinPackage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchStdlibExclude(t *testing.T) {
+	patterns := []string{"runtime", "internal/", "vendor/", "crypto/x509"}
+	tests := []struct {
+		importPath string
+		want       bool
+	}{
+		{"runtime", true},
+		{"runtime/debug", true},
+		{"runtimex", false},
+		{"internal/abi", true},
+		{"crypto/internal/boring", true},
+		{"log/internal", true},
+		{"vendor/golang.org/x/net/idna", true},
+		{"crypto/x509", true},
+		{"crypto/x509/pkix", true},
+		{"crypto/tls", false},
+		{"net/http", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			if got := MatchStdlibExclude(tt.importPath, patterns); got != tt.want {
+				t.Errorf("MatchStdlibExclude(%q) = %v, want %v", tt.importPath, got, tt.want)
+			}
+		})
+	}
+	if MatchStdlibExclude("runtime", nil) {
+		t.Error("no patterns excluded runtime")
+	}
+}
+
+func TestExpandStdlibGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", groups: nil, want: []string{}},
+		{name: "one group", groups: []string{"concurrency"}, want: []string{"context", "sync"}},
+		{name: "overlapping groups dedupe", groups: []string{"net", "crypto"}, want: []string{"crypto", "crypto/tls", "crypto/x509", "hash", "mime", "net"}},
+		{name: "repeated group", groups: []string{"encoding", "encoding"}, want: []string{"archive", "compress", "encoding"}},
+		{name: "unknown group", groups: []string{"net", "graphics"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandStdlibGroups(tt.groups)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("no error for %v", tt.groups)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandStdlibGroups(%v) = %v, want %v", tt.groups, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		importPath, prefix string
+		want               bool
+	}{
+		{"net", "net", true},
+		{"net/http", "net", true},
+		{"netip", "net", false},
+		{"crypto/tls", "crypto/tls", true},
+		{"crypto/x509", "crypto/tls", false},
+		{"internal/abi", "internal/", true},
+		{"internal", "internal/", false},
+	}
+	for _, tt := range tests {
+		if got := HasPathPrefix(tt.importPath, tt.prefix); got != tt.want {
+			t.Errorf("HasPathPrefix(%q, %q) = %v, want %v", tt.importPath, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestIsInternalPath(t *testing.T) {
+	tests := []struct {
+		importPath string
+		want       bool
+	}{
+		{"internal/poll", true},
+		{"crypto/internal/boring", true},
+		{"log/internal", true},
+		{"vendor/golang.org/x/net/idna", true},
+		{"net/http", false},
+		{"internalize", false},
+		{"go/internalish", false},
+	}
+	for _, tt := range tests {
+		if got := isInternalPath(tt.importPath); got != tt.want {
+			t.Errorf("isInternalPath(%q) = %v, want %v", tt.importPath, got, tt.want)
+		}
+	}
+}
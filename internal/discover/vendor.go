@@ -0,0 +1,82 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vendorModules lists the main module and the modules recorded in
+// vendor/modules.txt, for projects built with -mod=vendor where go list -m
+// all refuses to run. Vendored modules get their directory under vendor/.
+func vendorModules(ctx context.Context, dir string, run goRunner) ([]Module, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "vendor", "modules.txt"))
+	if err != nil {
+		return nil, err
+	}
+	output, err := run(ctx, dir, "list", "-m", "-json")
+	if err != nil {
+		return nil, err
+	}
+	var main Module
+	if err := json.Unmarshal(output, &main); err != nil {
+		return nil, err
+	}
+	return append([]Module{main}, parseModulesTxt(data, filepath.Join(dir, "vendor"))...), nil
+}
+
+// parseModulesTxt reads the "# path version [=> replacement [version]]"
+// module lines of a vendor/modules.txt, ignoring the "##" annotations and
+// package lines that follow each.
+func parseModulesTxt(data []byte, vendorDir string) []Module {
+	var modules []Module
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "# ")
+		if !ok {
+			continue
+		}
+		orig, replacement, replaced := strings.Cut(line, " => ")
+		fields := strings.Fields(orig)
+		if len(fields) == 0 {
+			continue
+		}
+		mod := Module{Path: fields[0], Dir: filepath.Join(vendorDir, filepath.FromSlash(fields[0]))}
+		if len(fields) > 1 {
+			mod.Version = fields[1]
+		}
+		if replaced {
+			if rep := strings.Fields(replacement); len(rep) > 0 {
+				mod.Replace = &Module{Path: rep[0]}
+				if len(rep) > 1 {
+					mod.Replace.Version = rep[1]
+				}
+			}
+		}
+		modules = append(modules, mod)
+	}
+	return modules
+}
+
+// vendorModuleDir returns the root of the vendored copy of module modPath
+// given one of its packages, or "" when pkgDir is not under a vendor
+// directory. Vendored packages live at vendor/<import path>, so the module
+// root is the package directory minus the import path below the module.
+func vendorModuleDir(pkgDir, importPath, modPath string) string {
+	if !strings.Contains(filepath.ToSlash(pkgDir), "/vendor/") {
+		return ""
+	}
+	sub := strings.TrimPrefix(strings.TrimPrefix(importPath, modPath), "/")
+	if sub == "" {
+		return pkgDir
+	}
+	dir, ok := strings.CutSuffix(pkgDir, string(filepath.Separator)+filepath.FromSlash(sub))
+	if !ok {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,138 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const modulesTxt = `# example.com/dep v1.2.0
+## explicit; go 1.21
+example.com/dep
+example.com/dep/sub
+# example.com/old v0.1.0 => example.com/fork v0.1.1
+## explicit
+example.com/old
+# example.com/local v0.0.0 => ../local
+example.com/local
+`
+
+// vendorFixture writes a project at a new directory with go.mod,
+// vendor/modules.txt and a vendored package example.com/dep/sub.
+func vendorFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                            "module example.com/m\n\ngo 1.21\n\nrequire example.com/dep v1.2.0\n",
+		"main.go":                           "package main\n\nimport _ \"example.com/dep/sub\"\n\nfunc main() {}\n",
+		"vendor/modules.txt":                modulesTxt,
+		"vendor/example.com/dep/dep.go":     "package dep\n",
+		"vendor/example.com/dep/sub/sub.go": "package sub\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestParseModulesTxt(t *testing.T) {
+	vendor := filepath.Join("repo", "vendor")
+	got := parseModulesTxt([]byte(modulesTxt), vendor)
+	want := []Module{
+		{Path: "example.com/dep", Version: "v1.2.0", Dir: filepath.Join(vendor, "example.com", "dep")},
+		{Path: "example.com/old", Version: "v0.1.0", Dir: filepath.Join(vendor, "example.com", "old"), Replace: &Module{Path: "example.com/fork", Version: "v0.1.1"}},
+		{Path: "example.com/local", Version: "v0.0.0", Dir: filepath.Join(vendor, "example.com", "local"), Replace: &Module{Path: "../local"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseModulesTxt = %+v, want %+v", got, want)
+	}
+}
+
+func TestVendorModuleDir(t *testing.T) {
+	vendored := filepath.Join("/repo", "vendor", "example.com", "dep")
+	tests := []struct {
+		name       string
+		pkgDir     string
+		importPath string
+		want       string
+	}{
+		{name: "module root", pkgDir: vendored, importPath: "example.com/dep", want: vendored},
+		{name: "subpackage", pkgDir: filepath.Join(vendored, "sub", "inner"), importPath: "example.com/dep/sub/inner", want: vendored},
+		{name: "not vendored", pkgDir: filepath.Join("/cache", "example.com", "dep@v1.2.0", "sub"), importPath: "example.com/dep/sub"},
+		{name: "path mismatch", pkgDir: filepath.Join(vendored, "other"), importPath: "example.com/dep/sub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vendorModuleDir(tt.pkgDir, tt.importPath, "example.com/dep"); got != tt.want {
+				t.Errorf("vendorModuleDir = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoListModulesVendorFallback(t *testing.T) {
+	root := vendorFixture(t)
+	run := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "list -m -json all":
+			return nil, errors.New("go: can't compute 'all' using the vendor directory")
+		case "list -m -json":
+			return []byte(`{"Path": "example.com/m", "Main": true, "Dir": "` + filepath.ToSlash(root) + `"}`), nil
+		}
+		return nil, errors.New("unexpected go " + strings.Join(args, " "))
+	}
+
+	modules, err := goListModules(context.Background(), root, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range modules {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{"example.com/m", "example.com/dep", "example.com/old", "example.com/local"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("modules = %v, want %v", paths, want)
+	}
+	if !modules[0].Main {
+		t.Error("first module is not the main module")
+	}
+	if want := filepath.Join(root, "vendor", "example.com", "dep"); modules[1].Dir != want {
+		t.Errorf("dep Dir = %q, want %q", modules[1].Dir, want)
+	}
+}
+
+func TestCollectThirdPartyVendored(t *testing.T) {
+	root := vendorFixture(t)
+	modDir := filepath.Join(root, "vendor", "example.com", "dep")
+	deps := []Package{
+		{ImportPath: "example.com/dep/sub", Dir: filepath.Join(modDir, "sub"), Module: &Module{Path: "example.com/dep", Version: "v1.2.0"}},
+		{ImportPath: "example.com/dep", Dir: modDir, Module: &Module{Path: "example.com/dep", Version: "v1.2.0"}},
+		{ImportPath: "fmt", Standard: true},
+		{ImportPath: "example.com/m", Dir: root, Module: &Module{Path: "example.com/m", Main: true}},
+	}
+
+	usage := collectThirdParty(deps, map[string]Module{}, "example.com/m")
+	if len(usage) != 1 {
+		t.Fatalf("got %d third-party modules, want 1: %+v", len(usage), usage)
+	}
+	if usage[0].Module.Dir != modDir {
+		t.Errorf("module Dir = %q, want %q", usage[0].Module.Dir, modDir)
+	}
+	var paths []string
+	for _, p := range usage[0].Packages {
+		paths = append(paths, p.ImportPath)
+	}
+	if want := []string{"example.com/dep", "example.com/dep/sub"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("packages = %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,150 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Providers accepted by New.
+const (
+	ProviderOpenAI = "openai"
+	ProviderLocal  = "local"
+)
+
+const (
+	// DefaultBatchSize is how many texts are sent per embedding request.
+	DefaultBatchSize = 64
+	// DefaultRetries is how many times a rate-limited or failed batch is retried.
+	DefaultRetries = 5
+)
+
+// Embedder turns texts into vectors. Implementations return exactly one
+// vector per input text, in input order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Settings configures the provider created by New. Empty fields fall back to
+// the provider's defaults.
+type Settings struct {
+	Model   string
+	URL     string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// New returns the Embedder for provider.
+func New(provider string, s Settings) (Embedder, error) {
+	client := &http.Client{Timeout: s.Timeout}
+	if s.Timeout == 0 {
+		client.Timeout = 60 * time.Second
+	}
+	switch provider {
+	case ProviderOpenAI:
+		if s.APIKey == "" {
+			return nil, errors.New("openai embeddings need an API key (set OPENAI_API_KEY)")
+		}
+		return &OpenAI{APIKey: s.APIKey, Model: s.Model, URL: s.URL, Client: client}, nil
+	case ProviderLocal:
+		return &Local{Model: s.Model, URL: s.URL, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (want %s or %s)", provider, ProviderOpenAI, ProviderLocal)
+	}
+}
+
+// RetryableError marks a failed request worth retrying, such as HTTP 429 or
+// a 5xx response. After, when set, is the server's requested wait.
+type RetryableError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// Batcher splits texts into batches for an Embedder and retries batches that
+// fail with a RetryableError, backing off exponentially between attempts.
+type Batcher struct {
+	Embedder  Embedder
+	BatchSize int
+	Retries   int
+	// Backoff is the wait before the first retry; it doubles on each attempt.
+	Backoff time.Duration
+	// Progress, when set, is called after each batch with the number of texts embedded so far.
+	Progress func(done, total int)
+}
+
+// Embed returns one vector per text, in order.
+func (b Batcher) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	size := b.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		batch, err := b.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("embedding texts %d-%d: %w", start, end-1, err)
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("embedding texts %d-%d: got %d vectors for %d texts", start, end-1, len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+		if b.Progress != nil {
+			b.Progress(end, len(texts))
+		}
+	}
+	return vectors, nil
+}
+
+// embedBatch sends one batch, retrying retryable failures.
+func (b Batcher) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	wait := b.Backoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		vectors, err := b.Embedder.Embed(ctx, texts)
+		var retry *RetryableError
+		if err == nil || !errors.As(err, &retry) || attempt >= b.Retries {
+			return vectors, err
+		}
+		delay := wait
+		if retry.After > 0 {
+			delay = retry.After
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+// statusError converts a non-2xx response into an error, retryable for 429
+// and 5xx statuses.
+func statusError(resp *http.Response, body []byte) error {
+	err := fmt.Errorf("%s: %s", resp.Status, truncate(string(body), 200))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return err
+	}
+	var after time.Duration
+	if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+		after = time.Duration(secs) * time.Second
+	}
+	return &RetryableError{Err: err, After: after}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,131 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder returns a one-element vector per text holding the text's
+// index in the whole input, recording each batch size. The first failures
+// calls return a RetryableError.
+type fakeEmbedder struct {
+	index    map[string]int
+	batches  []int
+	failures int
+	err      error
+}
+
+func (f *fakeEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, &RetryableError{Err: errors.New("429 Too Many Requests"), After: time.Millisecond}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.batches = append(f.batches, len(texts))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(f.index[text])}
+	}
+	return vectors, nil
+}
+
+func texts(n int) ([]string, map[string]int) {
+	out := make([]string, n)
+	index := make(map[string]int, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("text %d", i)
+		index[out[i]] = i
+	}
+	return out, index
+}
+
+func TestBatcherBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		batchSize int
+		want      []int
+	}{
+		{name: "exact multiple", n: 6, batchSize: 3, want: []int{3, 3}},
+		{name: "short last batch", n: 7, batchSize: 3, want: []int{3, 3, 1}},
+		{name: "one batch", n: 2, batchSize: 10, want: []int{2}},
+		{name: "default size", n: DefaultBatchSize + 1, want: []int{DefaultBatchSize, 1}},
+		{name: "empty", n: 0, batchSize: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, index := texts(tt.n)
+			fake := &fakeEmbedder{index: index}
+			var progress []int
+			b := Batcher{Embedder: fake, BatchSize: tt.batchSize, Progress: func(done, total int) {
+				if total != tt.n {
+					t.Errorf("progress total = %d, want %d", total, tt.n)
+				}
+				progress = append(progress, done)
+			}}
+			vectors, err := b.Embed(context.Background(), in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(fake.batches, tt.want) {
+				t.Errorf("batches = %v, want %v", fake.batches, tt.want)
+			}
+			if len(vectors) != tt.n {
+				t.Fatalf("got %d vectors, want %d", len(vectors), tt.n)
+			}
+			for i, v := range vectors {
+				if v[0] != float32(i) {
+					t.Errorf("vector %d is for text %v", i, v[0])
+				}
+			}
+			done := 0
+			for i, size := range tt.want {
+				done += size
+				if i >= len(progress) || progress[i] != done {
+					t.Errorf("progress = %v, want running totals of %v", progress, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBatcherRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		err      error
+		wantErr  bool
+	}{
+		{name: "retried until success", failures: 2, retries: 2},
+		{name: "retries exhausted", failures: 3, retries: 2, wantErr: true},
+		{name: "permanent error not retried", err: errors.New("400 Bad Request"), retries: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, index := texts(4)
+			fake := &fakeEmbedder{index: index, failures: tt.failures, err: tt.err}
+			b := Batcher{Embedder: fake, BatchSize: 4, Retries: tt.retries, Backoff: time.Millisecond}
+			vectors, err := b.Embed(context.Background(), in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Embed succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vectors) != 4 || fake.failures != 0 {
+				t.Errorf("got %d vectors with %d failures left", len(vectors), fake.failures)
+			}
+		})
+	}
+}
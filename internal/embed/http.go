@@ -0,0 +1,115 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// OpenAI embeds texts with the OpenAI embeddings API, or any server that
+// implements it.
+type OpenAI struct {
+	APIKey string
+	// Model defaults to text-embedding-3-small.
+	Model string
+	// URL defaults to https://api.openai.com/v1/embeddings.
+	URL    string
+	Client *http.Client
+}
+
+// Embed implements Embedder.
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	model, url := o.Model, o.URL
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	if url == "" {
+		url = "https://api.openai.com/v1/embeddings"
+	}
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	req := map[string]any{"model": model, "input": texts}
+	if err := postJSON(ctx, o.Client, url, "Bearer "+o.APIKey, req, &resp); err != nil {
+		return nil, err
+	}
+	// The API documents data as being in input order, but each item carries
+	// its index, so honour it.
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	vectors := make([][]float32, len(resp.Data))
+	for i, d := range resp.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// Local embeds texts with a local Ollama server's /api/embed endpoint.
+type Local struct {
+	// Model defaults to nomic-embed-text.
+	Model string
+	// URL defaults to http://localhost:11434/api/embed.
+	URL    string
+	Client *http.Client
+}
+
+// Embed implements Embedder.
+func (l *Local) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	model, url := l.Model, l.URL
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	if url == "" {
+		url = "http://localhost:11434/api/embed"
+	}
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	req := map[string]any{"model": model, "input": texts}
+	if err := postJSON(ctx, l.Client, url, "", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Embeddings, nil
+}
+
+// postJSON sends body to url and decodes a 2xx JSON response into out.
+// Transport errors are retryable, as are 429 and 5xx responses.
+func postJSON(ctx context.Context, client *http.Client, url, auth string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return &RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &RetryableError{Err: err}
+	}
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp, respBody)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", url, err)
+	}
+	return nil
+}
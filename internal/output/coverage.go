@@ -0,0 +1,8 @@
+package output
+
+import "github.com/natedelduca/go-rag-pack/internal/chunk"
+
+// WriteCoverage writes a build's declaration coverage as JSON.
+func WriteCoverage(path string, cov *chunk.Coverage) error {
+	return writeJSON(path, cov)
+}
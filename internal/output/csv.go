@@ -0,0 +1,32 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// csvHeader lists the columns WriteCSV emits, in order.
+var csvHeader = []string{"id", "importPath", "kind", "symbol", "source", "text"}
+
+// WriteCSV writes chunks as RFC 4180 CSV for review in a spreadsheet, one row
+// per chunk under a header row. Fields containing quotes, commas or newlines
+// are quoted, with embedded quotes doubled.
+func WriteCSV(path string, chunks []chunk.Chunk) error {
+	return writeAtomic(path, func(out io.Writer) error {
+		w := csv.NewWriter(out)
+		w.UseCRLF = true
+		if err := w.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, ch := range chunks {
+			md := ch.Metadata
+			if err := w.Write([]string{ch.ID, md.ImportPath, md.Kind, md.Symbol, md.Source, ch.Text}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
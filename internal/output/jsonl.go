@@ -2,33 +2,59 @@ package output
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/natedelduca/go-rag-pack/internal/chunk"
 )
 
-// WriteJSONL writes a slice of chunks to a newline-delimited JSON file.
+// WriteJSONL writes a slice of chunks to a newline-delimited JSON file,
+// gzip-compressed when path ends in .gz.
 func WriteJSONL(path string, chunks []chunk.Chunk) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
+	return writeAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, ch := range chunks {
+			if err := enc.Encode(ch); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	f, err := os.Create(path)
+// ReadJSONL loads the chunks of a file written by WriteJSONL, decompressing
+// a .gz file.
+func ReadJSONL(path string) ([]chunk.Chunk, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
-	writer := bufio.NewWriter(f)
-	enc := json.NewEncoder(writer)
-
-	for _, ch := range chunks {
-		if err := enc.Encode(ch); err != nil {
-			return err
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
 		}
+		defer gz.Close()
+		r = gz
+	}
+	var chunks []chunk.Chunk
+	dec := json.NewDecoder(r)
+	for {
+		var ch chunk.Chunk
+		if err := dec.Decode(&ch); err != nil {
+			if errors.Is(err, io.EOF) {
+				return chunks, nil
+			}
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		chunks = append(chunks, ch)
 	}
-
-	return writer.Flush()
 }
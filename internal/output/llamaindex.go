@@ -0,0 +1,116 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// LlamaIndex NodeRelationship keys, as serialised in a node's relationships.
+const (
+	llamaSource   = "1"
+	llamaPrevious = "2"
+	llamaNext     = "3"
+	llamaParent   = "4"
+	llamaChild    = "5"
+)
+
+// LlamaNode is a chunk in the JSON shape of a LlamaIndex TextNode.
+type LlamaNode struct {
+	ID            string         `json:"id_"`
+	Text          string         `json:"text"`
+	Metadata      map[string]any `json:"metadata"`
+	Relationships map[string]any `json:"relationships"`
+	Embedding     []float32      `json:"embedding,omitempty"`
+	ClassName     string         `json:"class_name"`
+}
+
+// LlamaRelated is a LlamaIndex RelatedNodeInfo reference to another node.
+type LlamaRelated struct {
+	NodeID    string `json:"node_id"`
+	ClassName string `json:"class_name"`
+}
+
+// LlamaNodes converts chunks to LlamaIndex nodes. Relationships come from
+// Relations: in-package edges become SOURCE, method-of and field-of become
+// PARENT on the member and CHILD on the type, and next-part edges become
+// NEXT and PREVIOUS.
+func LlamaNodes(chunks []chunk.Chunk) ([]LlamaNode, error) {
+	rels := make(map[string]map[string]any, len(chunks))
+	relate := func(from, key, to string) {
+		if rels[from] == nil {
+			rels[from] = make(map[string]any)
+		}
+		ref := LlamaRelated{NodeID: to, ClassName: "RelatedNodeInfo"}
+		if key == llamaChild {
+			children, _ := rels[from][key].([]LlamaRelated)
+			rels[from][key] = append(children, ref)
+			return
+		}
+		rels[from][key] = ref
+	}
+	for _, edge := range Relations(chunks) {
+		switch edge.Type {
+		case EdgeInPackage:
+			relate(edge.From, llamaSource, edge.To)
+		case EdgeMethodOf, EdgeFieldOf:
+			relate(edge.From, llamaParent, edge.To)
+			relate(edge.To, llamaChild, edge.From)
+		case EdgeNextPart:
+			relate(edge.From, llamaNext, edge.To)
+			relate(edge.To, llamaPrevious, edge.From)
+		}
+	}
+
+	nodes := make([]LlamaNode, 0, len(chunks))
+	for _, ch := range chunks {
+		metadata, err := metadataMap(ch.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		relationships := rels[ch.ID]
+		if relationships == nil {
+			relationships = map[string]any{}
+		}
+		nodes = append(nodes, LlamaNode{
+			ID:            ch.ID,
+			Text:          ch.Text,
+			Metadata:      metadata,
+			Relationships: relationships,
+			Embedding:     ch.Vector,
+			ClassName:     "TextNode",
+		})
+	}
+	return nodes, nil
+}
+
+// metadataMap flattens md into the same keys the JSONL output uses.
+func metadataMap(md chunk.Metadata) (map[string]any, error) {
+	data, err := json.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteLlamaIndex writes chunks as newline-delimited LlamaIndex TextNode JSON.
+func WriteLlamaIndex(path string, chunks []chunk.Chunk) error {
+	nodes, err := LlamaNodes(chunks)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, node := range nodes {
+			if err := enc.Encode(node); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
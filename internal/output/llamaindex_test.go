@@ -0,0 +1,134 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// llamaNodeJSON is the decoded form of a written node, with relationships
+// left raw so single references and child lists can both be read.
+type llamaNodeJSON struct {
+	ID            string                     `json:"id_"`
+	Text          string                     `json:"text"`
+	Metadata      json.RawMessage            `json:"metadata"`
+	Relationships map[string]json.RawMessage `json:"relationships"`
+	ClassName     string                     `json:"class_name"`
+}
+
+func readLlamaNodes(t *testing.T, path string) map[string]llamaNodeJSON {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	nodes := make(map[string]llamaNodeJSON)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var node llamaNodeJSON
+		if err := json.Unmarshal(scanner.Bytes(), &node); err != nil {
+			t.Fatalf("bad node line %s: %v", scanner.Text(), err)
+		}
+		nodes[node.ID] = node
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return nodes
+}
+
+// relatedIDs returns the node IDs a node's relationship key refers to.
+func relatedIDs(t *testing.T, node llamaNodeJSON, key string) []string {
+	t.Helper()
+	raw, ok := node.Relationships[key]
+	if !ok {
+		return nil
+	}
+	var refs []LlamaRelated
+	if key == llamaChild {
+		if err := json.Unmarshal(raw, &refs); err != nil {
+			t.Fatalf("%s relationship %s: %v", node.ID, key, err)
+		}
+	} else {
+		var ref LlamaRelated
+		if err := json.Unmarshal(raw, &ref); err != nil {
+			t.Fatalf("%s relationship %s: %v", node.ID, key, err)
+		}
+		refs = []LlamaRelated{ref}
+	}
+	var ids []string
+	for _, ref := range refs {
+		if ref.ClassName != "RelatedNodeInfo" {
+			t.Errorf("%s relationship %s has class %q", node.ID, key, ref.ClassName)
+		}
+		ids = append(ids, ref.NodeID)
+	}
+	return ids
+}
+
+func TestWriteLlamaIndexRoundTrip(t *testing.T) {
+	chunks := buildChunks(t, symbolsSrc, chunk.Options{MaxTokens: 20, FieldChunks: true})
+	path := filepath.Join(t.TempDir(), "nodes.jsonl")
+	if err := WriteLlamaIndex(path, chunks); err != nil {
+		t.Fatal(err)
+	}
+	nodes := readLlamaNodes(t, path)
+	if len(nodes) != len(chunks) {
+		t.Fatalf("got %d nodes, want %d", len(nodes), len(chunks))
+	}
+
+	for _, ch := range chunks {
+		node, ok := nodes[ch.ID]
+		if !ok {
+			t.Errorf("no node for %s", ch.ID)
+			continue
+		}
+		if node.Text != ch.Text || node.ClassName != "TextNode" {
+			t.Errorf("%s: text or class_name did not survive", ch.ID)
+		}
+		var md chunk.Metadata
+		if err := json.Unmarshal(node.Metadata, &md); err != nil {
+			t.Fatalf("%s: metadata: %v", ch.ID, err)
+		}
+		if !reflect.DeepEqual(md, ch.Metadata) {
+			t.Errorf("%s: metadata = %+v, want %+v", ch.ID, md, ch.Metadata)
+		}
+	}
+
+	type check struct {
+		node      llamaNodeJSON
+		key, want string
+	}
+	kinds := make(map[string]bool)
+	for _, edge := range Relations(chunks) {
+		from, to := nodes[edge.From], nodes[edge.To]
+		var checks []check
+		switch edge.Type {
+		case EdgeInPackage:
+			checks = append(checks, check{from, llamaSource, edge.To})
+		case EdgeMethodOf, EdgeFieldOf:
+			checks = append(checks, check{from, llamaParent, edge.To}, check{to, llamaChild, edge.From})
+		case EdgeNextPart:
+			checks = append(checks, check{from, llamaNext, edge.To}, check{to, llamaPrevious, edge.From})
+		}
+		for _, c := range checks {
+			if !slices.Contains(relatedIDs(t, c.node, c.key), c.want) {
+				t.Errorf("%s edge %s -> %s: node %s relationship %s lacks %s", edge.Type, edge.From, edge.To, c.node.ID, c.key, c.want)
+			}
+		}
+		kinds[edge.Type] = true
+	}
+	for _, kind := range []string{EdgeInPackage, EdgeMethodOf, EdgeFieldOf, EdgeNextPart} {
+		if !kinds[kind] {
+			t.Errorf("fixture produced no %s edge", kind)
+		}
+	}
+}
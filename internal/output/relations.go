@@ -0,0 +1,97 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// Edge types emitted by Relations.
+const (
+	EdgeMethodOf  = "method-of"
+	EdgeFieldOf   = "field-of"
+	EdgeInPackage = "in-package"
+	EdgeNextPart  = "next-part"
+)
+
+// Edge is a typed, directed relationship between two chunk IDs.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// Relations derives the cross-chunk edges implied by chunk metadata:
+// methods and fields to their type, symbols to their package-doc chunk, and
+// each split part (including package-toc shards) to the part after it. Every edge endpoint is the ID of a
+// chunk in chunks. Edges are sorted by from, type, then to.
+func Relations(chunks []chunk.Chunk) []Edge {
+	ids := make(map[string]struct{}, len(chunks))
+	types := make(map[string]string)
+	packages := make(map[string]string)
+	for _, ch := range chunks {
+		ids[ch.ID] = struct{}{}
+		md := ch.Metadata
+		switch {
+		case md.Kind == "type" && md.Part <= 1:
+			types[md.ImportPath+" "+strings.TrimPrefix(md.Symbol, "type ")] = ch.ID
+		case md.Kind == "package-doc":
+			packages[md.ImportPath] = ch.ID
+		}
+	}
+
+	var edges []Edge
+	for _, ch := range chunks {
+		md := ch.Metadata
+		if md.ReceiverType != "" {
+			if to, ok := types[md.ImportPath+" "+md.ReceiverType]; ok {
+				edges = append(edges, Edge{From: ch.ID, To: to, Type: EdgeMethodOf})
+			}
+		}
+		if md.Kind == "field" {
+			owner, _, _ := strings.Cut(strings.TrimPrefix(md.Symbol, "field "), ".")
+			if to, ok := types[md.ImportPath+" "+owner]; ok {
+				edges = append(edges, Edge{From: ch.ID, To: to, Type: EdgeFieldOf})
+			}
+		}
+		if md.Symbol != "" && md.Part <= 1 {
+			if to, ok := packages[md.ImportPath]; ok {
+				edges = append(edges, Edge{From: ch.ID, To: to, Type: EdgeInPackage})
+			}
+		}
+		if md.Part > 0 && md.Part < md.Parts {
+			marker := ":part-"
+			if md.Kind == "package-toc" {
+				marker = ":toc-part-"
+			}
+			if base, ok := strings.CutSuffix(ch.ID, fmt.Sprintf("%s%d", marker, md.Part)); ok {
+				next := fmt.Sprintf("%s%s%d", base, marker, md.Part+1)
+				if _, ok := ids[next]; ok {
+					edges = append(edges, Edge{From: ch.ID, To: next, Type: EdgeNextPart})
+				}
+			}
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].Type != edges[j].Type {
+			return edges[i].Type < edges[j].Type
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// WriteRelations writes the edges derived from chunks as a JSON array.
+func WriteRelations(path string, chunks []chunk.Chunk) error {
+	edges := Relations(chunks)
+	if edges == nil {
+		edges = []Edge{}
+	}
+	return writeJSON(path, edges)
+}
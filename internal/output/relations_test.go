@@ -0,0 +1,76 @@
+package output
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+func TestRelationsEndpointsExist(t *testing.T) {
+	tests := []struct {
+		name string
+		opts chunk.Options
+	}{
+		{name: "split parts and fields", opts: chunk.Options{MaxTokens: 20, FieldChunks: true}},
+		{name: "toc shards", opts: chunk.Options{MaxTOCBytes: 10, FieldChunks: true, MaxTokens: 20}},
+		{name: "stable IDs", opts: chunk.Options{StableIDs: true, MaxTokens: 20, FieldChunks: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := buildChunks(t, symbolsSrc, tt.opts)
+			ids := make(map[string]chunk.Chunk, len(chunks))
+			for _, ch := range chunks {
+				ids[ch.ID] = ch
+			}
+
+			edges := Relations(chunks)
+			types := make(map[string]int)
+			for _, edge := range edges {
+				from, okFrom := ids[edge.From]
+				to, okTo := ids[edge.To]
+				if !okFrom || !okTo {
+					t.Errorf("%s edge %s -> %s points outside the chunks", edge.Type, edge.From, edge.To)
+					continue
+				}
+				if edge.From == edge.To {
+					t.Errorf("%s edge loops on %s", edge.Type, edge.From)
+				}
+				switch edge.Type {
+				case EdgeMethodOf, EdgeFieldOf:
+					if to.Metadata.Kind != "type" {
+						t.Errorf("%s edge from %s ends at a %s chunk", edge.Type, edge.From, to.Metadata.Kind)
+					}
+				case EdgeInPackage:
+					if to.Metadata.Kind != "package-doc" {
+						t.Errorf("in-package edge from %s ends at a %s chunk", edge.From, to.Metadata.Kind)
+					}
+				case EdgeNextPart:
+					if to.Metadata.Part != from.Metadata.Part+1 || to.Metadata.Parts != from.Metadata.Parts {
+						t.Errorf("next-part %s (part %d) -> %s (part %d)", edge.From, from.Metadata.Part, edge.To, to.Metadata.Part)
+					}
+				default:
+					t.Errorf("unknown edge type %q", edge.Type)
+				}
+				types[edge.Type]++
+			}
+			for _, typ := range []string{EdgeMethodOf, EdgeFieldOf, EdgeInPackage, EdgeNextPart} {
+				if types[typ] == 0 {
+					t.Errorf("no %s edge", typ)
+				}
+			}
+			if !slices.IsSortedFunc(edges, func(a, b Edge) int {
+				if c := strings.Compare(a.From, b.From); c != 0 {
+					return c
+				}
+				if c := strings.Compare(a.Type, b.Type); c != 0 {
+					return c
+				}
+				return strings.Compare(a.To, b.To)
+			}) {
+				t.Error("edges are not sorted by from, type, then to")
+			}
+		})
+	}
+}
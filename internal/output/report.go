@@ -0,0 +1,61 @@
+package output
+
+import (
+	"time"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// BuildReport summarises the outcome of a build for CI dashboards. Unlike
+// the versions lockfile, which records the inputs, it describes what came
+// out.
+type BuildReport struct {
+	// Outputs are the files or directory the chunks were written to.
+	Outputs []string `json:"outputs"`
+	Chunks  int      `json:"chunks"`
+	// TextBytes is the total size of the chunk texts.
+	TextBytes int            `json:"textBytes"`
+	ByKind    map[string]int `json:"byKind"`
+	BySource  map[string]int `json:"bySource"`
+	// DurationMs is the wall time of the build in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+	// Warnings holds every warning and error logged during the build.
+	Warnings []ReportWarning `json:"warnings"`
+}
+
+// ReportWarning is one logged warning with its fields, such as module or
+// path.
+type ReportWarning struct {
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// NewBuildReport counts chunks by kind and source and sums their text size.
+func NewBuildReport(chunks []chunk.Chunk, outputs []string, duration time.Duration, warnings []ReportWarning) BuildReport {
+	r := BuildReport{
+		Outputs:    outputs,
+		Chunks:     len(chunks),
+		ByKind:     make(map[string]int),
+		BySource:   make(map[string]int),
+		DurationMs: duration.Milliseconds(),
+		Warnings:   warnings,
+	}
+	for _, ch := range chunks {
+		r.TextBytes += len(ch.Text)
+		r.ByKind[ch.Metadata.Kind]++
+		r.BySource[ch.Metadata.Source]++
+	}
+	if r.Outputs == nil {
+		r.Outputs = []string{}
+	}
+	if r.Warnings == nil {
+		r.Warnings = []ReportWarning{}
+	}
+	return r
+}
+
+// WriteBuildReport writes r as JSON.
+func WriteBuildReport(path string, r BuildReport) error {
+	return writeJSON(path, r)
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// schemaDraft is the JSON Schema dialect ChunkSchema produces.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ChunkSchema returns a JSON Schema document describing one line of
+// WriteJSONL output. It is derived from chunk.Chunk by reflection, so it
+// always matches the structs: fields without omitempty are required, and
+// nested structs are described under $defs.
+func ChunkSchema() map[string]any {
+	defs := make(map[string]any)
+	root := structSchema(reflect.TypeOf(chunk.Chunk{}), defs)
+	root["$schema"] = schemaDraft
+	root["title"] = "go-rag-pack chunk"
+	root["$defs"] = defs
+	return root
+}
+
+// typeSchema describes t, adding named structs to defs and returning a
+// $ref to them.
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // break cycles before recursing
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// structSchema describes the JSON-encoded fields of struct type t.
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = typeSchema(field.Type, defs)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,146 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// validate checks v, decoded from JSON, against the subset of JSON Schema
+// that ChunkSchema emits, returning every mismatch found.
+func validate(v any, schema, defs map[string]any, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: dangling $ref %s", at, ref)}
+		}
+		return validate(v, def, defs, at)
+	}
+	var errs []string
+	switch schema["type"] {
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s: %v is not a string", at, v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s: %v is not a boolean", at, v))
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			errs = append(errs, fmt.Sprintf("%s: %v is not an integer", at, v))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			errs = append(errs, fmt.Sprintf("%s: %v is not a number", at, v))
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not an array", at, v))
+		}
+		for i, item := range items {
+			errs = append(errs, validate(item, schema["items"].(map[string]any), defs, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: %v is not an object", at, v))
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range schema["required"].([]any) {
+			if _, ok := obj[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required %s", at, name))
+			}
+		}
+		for name, val := range obj {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+					errs = append(errs, validate(val, extra, defs, at+"."+name)...)
+				} else {
+					errs = append(errs, fmt.Sprintf("%s: property %s is not in the schema", at, name))
+				}
+				continue
+			}
+			errs = append(errs, validate(val, prop, defs, at+"."+name)...)
+		}
+	}
+	return errs
+}
+
+// decodeJSON round-trips v through encoding/json into generic values.
+func decodeJSON(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestChunkSchemaValidatesOutput(t *testing.T) {
+	schema := decodeJSON(t, ChunkSchema()).(map[string]any)
+	defs := schema["$defs"].(map[string]any)
+	if schema["$schema"] != schemaDraft {
+		t.Errorf("$schema = %v", schema["$schema"])
+	}
+
+	chunks := buildChunks(t, symbolsSrc, chunk.Options{
+		MaxTokens:      20,
+		FieldChunks:    true,
+		SearchKeywords: true,
+		Topics:         []chunk.TopicRule{{Pattern: "*", Topic: "all"}},
+	})
+	chunks[0].Vector = []float32{0.5, -1}
+	for _, ch := range chunks {
+		for _, err := range validate(decodeJSON(t, ch), schema, defs, ch.ID) {
+			t.Error(err)
+		}
+	}
+
+	bad := decodeJSON(t, chunks[0]).(map[string]any)
+	bad["extra"] = true
+	delete(bad, "text")
+	bad["metadata"].(map[string]any)["part"] = "one"
+	if errs := validate(bad, schema, defs, "bad"); len(errs) != 3 {
+		t.Errorf("tampered chunk gave %d errors, want 3: %q", len(errs), errs)
+	}
+}
+
+func TestChunkSchemaCoversStructs(t *testing.T) {
+	schema := decodeJSON(t, ChunkSchema()).(map[string]any)
+	defs := schema["$defs"].(map[string]any)
+	for _, tt := range []struct {
+		typ   reflect.Type
+		props map[string]any
+		req   []any
+	}{
+		{reflect.TypeOf(chunk.Chunk{}), schema["properties"].(map[string]any), schema["required"].([]any)},
+		{reflect.TypeOf(chunk.Metadata{}), defs["Metadata"].(map[string]any)["properties"].(map[string]any), defs["Metadata"].(map[string]any)["required"].([]any)},
+	} {
+		for i := range tt.typ.NumField() {
+			field := tt.typ.Field(i)
+			tag := field.Tag.Get("json")
+			if !field.IsExported() || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if _, ok := tt.props[name]; !ok {
+				t.Errorf("%s.%s (%s) is missing from the schema", tt.typ.Name(), field.Name, name)
+			}
+			if required := slices.Contains(tt.req, any(name)); required == strings.Contains(opts, "omitempty") {
+				t.Errorf("%s.%s: required = %v, but omitempty is %v", tt.typ.Name(), field.Name, required, strings.Contains(opts, "omitempty"))
+			}
+		}
+	}
+}
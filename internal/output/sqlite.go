@@ -0,0 +1,104 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+const sqliteSchema = `
+CREATE TABLE chunks (
+	id             TEXT PRIMARY KEY,
+	text           TEXT NOT NULL,
+	path           TEXT NOT NULL,
+	package        TEXT NOT NULL,
+	import_path    TEXT NOT NULL,
+	module         TEXT NOT NULL,
+	module_version TEXT NOT NULL,
+	symbol         TEXT NOT NULL,
+	kind           TEXT NOT NULL,
+	source         TEXT NOT NULL,
+	metadata       TEXT NOT NULL
+);
+CREATE INDEX chunks_import_path ON chunks (import_path);
+CREATE INDEX chunks_symbol ON chunks (symbol);
+CREATE INDEX chunks_kind ON chunks (kind);
+CREATE VIRTUAL TABLE chunks_fts USING fts5 (symbol, text, content = 'chunks', content_rowid = 'rowid');
+`
+
+// WriteSQLite writes chunks into a fresh SQLite database at path. The chunks
+// table holds the text, the commonly queried metadata as indexed columns and
+// the full metadata as JSON; chunks_fts is an FTS5 index over symbol and text:
+//
+//	SELECT c.id FROM chunks_fts JOIN chunks c ON c.rowid = chunks_fts.rowid
+//	WHERE chunks_fts MATCH 'handler' ORDER BY rank;
+//
+// The database is built under a temporary name next to path and renamed
+// over any existing file only once it is complete.
+func WriteSQLite(path string, chunks []chunk.Chunk) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := writeSQLite(tmp, chunks); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// writeSQLite creates the database at path and closes it before returning.
+func writeSQLite(path string, chunks []chunk.Chunk) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO chunks
+		(id, text, path, package, import_path, module, module_version, symbol, kind, source, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, ch := range chunks {
+		md := ch.Metadata
+		meta, err := json.Marshal(md)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(ch.ID, ch.Text, md.Path, md.PackageName, md.ImportPath, md.ModulePath,
+			md.ModuleVersion, md.Symbol, md.Kind, md.Source, string(meta)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO chunks_fts (rowid, symbol, text) SELECT rowid, symbol, text FROM chunks`); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+func TestWriteSQLiteFullText(t *testing.T) {
+	chunks := []chunk.Chunk{
+		{ID: "a.go:ServeHTTP", Text: "ServeHTTP dispatches the request to the handler.", Metadata: chunk.Metadata{Symbol: "func (m *Mux) ServeHTTP", Kind: "function", ImportPath: "example.com/m", Source: "project"}},
+		{ID: "a.go:Dial", Text: "Dial connects to the address on the named network.", Metadata: chunk.Metadata{Symbol: "func Dial", Kind: "function", ImportPath: "example.com/m", Source: "project"}},
+		{ID: "b.go:type:Handler", Text: "A Handler responds to an HTTP request.", Metadata: chunk.Metadata{Symbol: "type Handler", Kind: "type", ImportPath: "example.com/m", Source: "project"}},
+	}
+	path := filepath.Join(t.TempDir(), "chunks.db")
+	if err := WriteSQLite(path, chunks); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"handler", []string{"a.go:ServeHTTP", "b.go:type:Handler"}},
+		{"network", []string{"a.go:Dial"}},
+		{"dial OR request", []string{"a.go:Dial", "a.go:ServeHTTP", "b.go:type:Handler"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rows, err := db.Query(`SELECT c.id FROM chunks_fts JOIN chunks c ON c.rowid = chunks_fts.rowid WHERE chunks_fts MATCH ?`, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got []string
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, id)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MATCH %q = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	var kind string
+	if err := db.QueryRow(`SELECT kind FROM chunks WHERE id = ?`, "b.go:type:Handler").Scan(&kind); err != nil || kind != "type" {
+		t.Errorf("kind column = %q, %v; want type", kind, err)
+	}
+}
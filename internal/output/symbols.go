@@ -0,0 +1,135 @@
+package output
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// SymbolEntry is a single record in the flat symbol index.
+type SymbolEntry struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	ImportPath string `json:"importPath"`
+	Signature  string `json:"signature"`
+	ID         string `json:"id"`
+}
+
+// declKinds are the chunk kinds that declare a symbol. Type bundles, usage
+// examples, module info and command summaries carry a symbol too, but only
+// describe it, so the indexes leave them out.
+var declKinds = map[string]bool{
+	"function":  true,
+	"signature": true,
+	"type":      true,
+	"field":     true,
+	"const":     true,
+	"var":       true,
+}
+
+// indexed reports whether md is the chunk the symbol indexes point at for
+// its declaration: a declaration kind and, when split, the first part.
+func indexed(md chunk.Metadata) bool {
+	return md.Symbol != "" && declKinds[md.Kind] && md.Part <= 1
+}
+
+// SymbolIndex derives one entry per declared name from chunk metadata, in chunk order.
+// Only declaration chunks are listed, so each name appears once however many
+// chunks describe it, and split declarations point at their first part.
+func SymbolIndex(chunks []chunk.Chunk) []SymbolEntry {
+	var entries []SymbolEntry
+	for _, ch := range chunks {
+		md := ch.Metadata
+		if !indexed(md) {
+			continue
+		}
+		signature := md.Signature
+		if signature == "" {
+			signature = md.Symbol
+		}
+		for _, name := range chunk.SymbolNames(md.Symbol) {
+			entries = append(entries, SymbolEntry{
+				Name:       name,
+				Kind:       md.Kind,
+				ImportPath: md.ImportPath,
+				Signature:  signature,
+				ID:         ch.ID,
+			})
+		}
+	}
+	return entries
+}
+
+// WriteSymbolIndex writes the flat symbol index for chunks as a compact JSON array.
+func WriteSymbolIndex(path string, chunks []chunk.Chunk) error {
+	entries := SymbolIndex(chunks)
+	if entries == nil {
+		entries = []SymbolEntry{}
+	}
+	return writeJSON(path, entries)
+}
+
+// IndexEntry locates one chunk declaring a symbol.
+type IndexEntry struct {
+	ImportPath string `json:"importPath"`
+	Kind       string `json:"kind"`
+	ID         string `json:"id"`
+}
+
+// Index maps every exported symbol name, methods and fields as Type.Name,
+// to the chunks declaring it, so a lookup tool can jump from a name to a
+// chunk. Like SymbolIndex it lists declaration chunks only, and split
+// declarations point at their first part. A name declared in several
+// packages lists each, sorted by import path and ID.
+func Index(chunks []chunk.Chunk) map[string][]IndexEntry {
+	index := make(map[string][]IndexEntry)
+	for _, ch := range chunks {
+		md := ch.Metadata
+		if !indexed(md) {
+			continue
+		}
+		for _, name := range chunk.SymbolNames(md.Symbol) {
+			if !exportedName(name) {
+				continue
+			}
+			index[name] = append(index[name], IndexEntry{ImportPath: md.ImportPath, Kind: md.Kind, ID: ch.ID})
+		}
+	}
+	for _, entries := range index {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].ImportPath != entries[j].ImportPath {
+				return entries[i].ImportPath < entries[j].ImportPath
+			}
+			return entries[i].ID < entries[j].ID
+		})
+	}
+	return index
+}
+
+// WriteIndex writes the symbol lookup index for chunks as a compact JSON
+// object, keys sorted by symbol name so successive builds diff cleanly.
+func WriteIndex(path string, chunks []chunk.Chunk) error {
+	return writeJSON(path, Index(chunks))
+}
+
+// exportedName reports whether every dot-separated part of name, such as
+// both Type and Method in Type.Method, is exported.
+func exportedName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !token.IsExported(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSON atomically writes v as compact JSON to path, creating parent directories.
+func writeJSON(path string, v any) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// buildChunks chunks src, written as a.go of the project package
+// example.com/m, with opts.
+func buildChunks(t *testing.T, src string, opts chunk.Options) []chunk.Chunk {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := chunk.Build([]chunk.PackageSource{{
+		ModulePath: "example.com/m",
+		ModuleDir:  dir,
+		ImportPath: "example.com/m",
+		Dir:        dir,
+		Kind:       chunk.SourceProject,
+	}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+const symbolsSrc = `// Package m is for tests.
+package m
+
+// Server serves.
+type Server struct {
+	Name string
+}
+
+// Start starts the server.
+func (s *Server) Start() {
+	// A body long enough to be split by a small token limit, so the
+	// index has to cope with declarations in several parts.
+	_ = s.Name
+	_ = s.Name
+	_ = s.Name
+	_ = s.Name
+}
+
+// Run runs.
+func Run() {}
+
+func helper() { Run() }
+
+const (
+	A, B = 1, 2
+)
+
+var Default = Server{}
+`
+
+// withModuleInfo appends the module-info chunk of a dependency module, whose
+// Symbol is its module path, to chunks.
+func withModuleInfo(t *testing.T, chunks []chunk.Chunk) []chunk.Chunk {
+	t.Helper()
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(goMod, []byte("module example.com/dep\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := chunk.BuildModuleInfo(chunk.ModuleSource{Path: "example.com/dep", Version: "v1.0.0", GoMod: goMod, Kind: chunk.SourceThirdParty}, chunk.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 1 {
+		t.Fatalf("got %d module-info chunks, want 1", len(info))
+	}
+	return append(chunks, info...)
+}
+
+// symbolIndexCases are option sets under which every symbol must still be
+// indexed once, including those that add chunks describing a symbol.
+var symbolIndexCases = []struct {
+	name       string
+	opts       chunk.Options
+	moduleInfo bool
+}{
+	{name: "default"},
+	{name: "split declarations", opts: chunk.Options{MaxTokens: 20}},
+	{name: "field chunks", opts: chunk.Options{FieldChunks: true}},
+	{name: "type bundles", opts: chunk.Options{TypeBundles: true}},
+	{name: "usage examples", opts: chunk.Options{UsageExamples: 2}},
+	{name: "module info", moduleInfo: true},
+	{name: "everything", opts: chunk.Options{MaxTokens: 20, FieldChunks: true, TypeBundles: true, UsageExamples: 2}, moduleInfo: true},
+}
+
+func TestSymbolIndexListsEachSymbolOnce(t *testing.T) {
+	for _, tt := range symbolIndexCases {
+		opts := tt.opts
+		chunks := buildChunks(t, symbolsSrc, opts)
+		if tt.moduleInfo {
+			chunks = withModuleInfo(t, chunks)
+		}
+		counts := make(map[string]int)
+		for _, entry := range SymbolIndex(chunks) {
+			if entry.ImportPath != "example.com/m" || entry.ID == "" || entry.Signature == "" {
+				t.Errorf("incomplete entry %+v", entry)
+			}
+			counts[entry.Name]++
+		}
+		want := []string{"Server", "Server.Start", "Run", "helper", "A", "B", "Default"}
+		if opts.FieldChunks {
+			want = append(want, "Server.Name")
+		}
+		for _, name := range want {
+			if counts[name] != 1 {
+				t.Errorf("%s: %s listed %d times, want once", tt.name, name, counts[name])
+			}
+		}
+		if len(counts) != len(want) {
+			t.Errorf("%s: index has names %v, want %v", tt.name, counts, want)
+		}
+	}
+}
+
+func TestIndexExportedOnly(t *testing.T) {
+	for _, tt := range symbolIndexCases {
+		chunks := buildChunks(t, symbolsSrc, tt.opts)
+		if tt.moduleInfo {
+			chunks = withModuleInfo(t, chunks)
+		}
+		index := Index(chunks)
+		want := []string{"Server", "Server.Start", "Run", "A", "B", "Default"}
+		if tt.opts.FieldChunks {
+			want = append(want, "Server.Name")
+		}
+		for _, name := range want {
+			if len(index[name]) != 1 {
+				t.Errorf("%s: %s has %d entries, want 1", tt.name, name, len(index[name]))
+			}
+		}
+		if _, ok := index["helper"]; ok {
+			t.Errorf("%s: unexported helper is indexed", tt.name)
+		}
+		if len(index) != len(want) {
+			t.Errorf("%s: index has %d names, want %d", tt.name, len(index), len(want))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Lockfile records the module versions a build indexed, so a later build can
+// tell when its output has gone stale.
+type Lockfile struct {
+	GoVersion  string         `json:"goVersion"`
+	MainModule LockedModule   `json:"mainModule"`
+	Modules    []LockedModule `json:"modules"`
+}
+
+// LockedModule is a module path and version, with its replacement if any.
+type LockedModule struct {
+	Path    string        `json:"path"`
+	Version string        `json:"version,omitempty"`
+	Replace *LockedModule `json:"replace,omitempty"`
+}
+
+// String renders m as path@version, followed by its replacement.
+func (m LockedModule) String() string {
+	s := m.Path
+	if m.Version != "" {
+		s += "@" + m.Version
+	}
+	if m.Replace != nil {
+		s += " => " + m.Replace.String()
+	}
+	return s
+}
+
+// WriteLockfile writes lf as JSON with its modules sorted by path.
+func WriteLockfile(path string, lf Lockfile) error {
+	sort.Slice(lf.Modules, func(i, j int) bool {
+		return lf.Modules[i].Path < lf.Modules[j].Path
+	})
+	return writeJSON(path, lf)
+}
+
+// ReadLockfile loads a lockfile written by WriteLockfile.
+func ReadLockfile(path string) (Lockfile, error) {
+	var lf Lockfile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lf, err
+	}
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return lf, fmt.Errorf("%s: %w", path, err)
+	}
+	return lf, nil
+}
+
+// Changes lists, sorted, how lf differs from prev: the Go version and each
+// module added, removed or moved to another version or replacement.
+func (lf Lockfile) Changes(prev Lockfile) []string {
+	var changes []string
+	if lf.GoVersion != prev.GoVersion {
+		changes = append(changes, fmt.Sprintf("go %s -> %s", prev.GoVersion, lf.GoVersion))
+	}
+	before := make(map[string]LockedModule, len(prev.Modules))
+	for _, m := range prev.Modules {
+		before[m.Path] = m
+	}
+	after := make(map[string]LockedModule, len(lf.Modules))
+	for _, m := range lf.Modules {
+		after[m.Path] = m
+		old, ok := before[m.Path]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("added %s", m))
+		case old.String() != m.String():
+			changes = append(changes, fmt.Sprintf("%s -> %s", old, m))
+		}
+	}
+	for _, m := range prev.Modules {
+		if _, ok := after[m.Path]; !ok {
+			changes = append(changes, fmt.Sprintf("removed %s", m))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLockfileRoundTripAndChanges(t *testing.T) {
+	prev := Lockfile{
+		GoVersion:  "go1.22.0",
+		MainModule: LockedModule{Path: "example.com/m"},
+		Modules: []LockedModule{
+			{Path: "example.com/b", Version: "v0.3.0"},
+			{Path: "example.com/a", Version: "v1.2.0"},
+			{Path: "example.com/gone", Version: "v1.0.0"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "versions.json")
+	if err := WriteLockfile(path, prev); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, prev) {
+		t.Errorf("ReadLockfile = %+v, want %+v", read, prev)
+	}
+	if !slices.IsSortedFunc(read.Modules, func(a, b LockedModule) int { return strings.Compare(a.Path, b.Path) }) {
+		t.Errorf("modules not sorted by path: %+v", read.Modules)
+	}
+
+	next := Lockfile{
+		GoVersion:  "go1.23.0",
+		MainModule: prev.MainModule,
+		Modules: []LockedModule{
+			{Path: "example.com/a", Version: "v1.2.0"},
+			{Path: "example.com/b", Version: "v0.3.0", Replace: &LockedModule{Path: "../b"}},
+			{Path: "example.com/new", Version: "v0.1.0"},
+		},
+	}
+	want := []string{
+		"added example.com/new@v0.1.0",
+		"example.com/b@v0.3.0 -> example.com/b@v0.3.0 => ../b",
+		"go go1.22.0 -> go1.23.0",
+		"removed example.com/gone@v1.0.0",
+	}
+	if got := next.Changes(read); !slices.Equal(got, want) {
+		t.Errorf("Changes = %q, want %q", got, want)
+	}
+	if got := read.Changes(read); len(got) != 0 {
+		t.Errorf("Changes against itself = %q, want none", got)
+	}
+}
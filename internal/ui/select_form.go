@@ -10,13 +10,19 @@ import (
 	"github.com/natedelduca/go-rag-pack/internal/discover"
 )
 
-// Selection captures the user's choices from the interactive form.
+// Selection captures the user's choices from the interactive form, or from
+// a selection file read by LoadSelection.
 type Selection struct {
-	IncludeProject  bool
-	IncludeStdlib   bool
-	IncludeModules  bool
-	SelectedModules []string
-	ManualModules   []string
+	IncludeProject  bool     `json:"includeProject"`
+	IncludeStdlib   bool     `json:"includeStdlib"`
+	IncludeModules  bool     `json:"includeModules"`
+	SelectedModules []string `json:"selectedModules,omitempty"`
+	ManualModules   []string `json:"manualModules,omitempty"`
+	// ExcludeStdlib holds the stdlib import path prefixes to leave out.
+	ExcludeStdlib []string `json:"excludeStdlib,omitempty"`
+	// SelectedPackages narrows a selected module to specific import paths.
+	// Modules without an entry keep all of their packages.
+	SelectedPackages map[string][]string `json:"selectedPackages,omitempty"`
 }
 
 // RunSelection displays the Charmbracelet/huh form and returns the user's selection.
@@ -61,6 +67,21 @@ func RunSelection(proj discover.Project, current config.Config) (Selection, erro
 		}
 	}
 
+	if selection.IncludeStdlib {
+		exclude := strings.Join(current.ExcludeStdlib, ", ")
+		excludeForm := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Skip stdlib packages (comma separated prefixes, e.g. runtime, internal/)").
+					Value(&exclude),
+			),
+		)
+		if err := excludeForm.Run(); err != nil {
+			return Selection{}, err
+		}
+		selection.ExcludeStdlib = splitModules(exclude)
+	}
+
 	if selection.IncludeModules && len(proj.ThirdParty) > 0 {
 		moduleOptions := make([]huh.Option[string], 0, len(proj.ThirdParty))
 		moduleDefaults := make(map[string]struct{})
@@ -80,6 +101,9 @@ func RunSelection(proj discover.Project, current config.Config) (Selection, erro
 			if mu.Module.Version != "" {
 				label = fmt.Sprintf("%s@%s", mu.Module.Path, mu.Module.Version)
 			}
+			if mu.Relation != "" {
+				label = fmt.Sprintf("%s (%s)", label, mu.Relation)
+			}
 			moduleOptions = append(moduleOptions, huh.NewOption(label, mu.Module.Path))
 			if _, ok := moduleDefaults[mu.Module.Path]; ok {
 				value = append(value, mu.Module.Path)
@@ -99,6 +123,12 @@ func RunSelection(proj discover.Project, current config.Config) (Selection, erro
 		}
 		selection.SelectedModules = value
 
+		packages, err := selectPackages(proj, current, value)
+		if err != nil {
+			return Selection{}, err
+		}
+		selection.SelectedPackages = packages
+
 		var manual string
 		if len(current.ManualModules) > 0 {
 			manual = strings.Join(current.ManualModules, ", ")
@@ -107,21 +137,176 @@ func RunSelection(proj discover.Project, current config.Config) (Selection, erro
 			huh.NewGroup(
 				huh.NewInput().
 					Title("Extra modules (comma separated, optional)").
-					Value(&manual),
+					Value(&manual).
+					Validate(func(s string) error {
+						return validateModules(splitModules(s), proj.AllModules)
+					}),
 			),
 		)
 		if err := manualForm.Run(); err != nil {
 			return Selection{}, err
 		}
-		if trimmed := strings.TrimSpace(manual); trimmed != "" {
-			parts := strings.Split(trimmed, ",")
-			for _, part := range parts {
-				if name := strings.TrimSpace(part); name != "" {
-					selection.ManualModules = append(selection.ManualModules, name)
-				}
+		selection.ManualModules = splitModules(manual)
+	}
+
+	return selection, nil
+}
+
+// selectPackages optionally lets the user narrow each selected module down to
+// some of its packages. Only modules where a subset was chosen are returned.
+func selectPackages(proj discover.Project, current config.Config, modules []string) (map[string][]string, error) {
+	usage := make(map[string]discover.ModuleUsage, len(proj.ThirdParty))
+	for _, mu := range proj.ThirdParty {
+		usage[mu.Module.Path] = mu
+	}
+
+	var candidates []discover.ModuleUsage
+	for _, path := range modules {
+		if mu, ok := usage[path]; ok && len(mu.Packages) > 1 {
+			candidates = append(candidates, mu)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	drill := len(current.SelectedPackages) > 0
+	confirmForm := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Pick individual packages within modules?").
+				Value(&drill),
+		),
+	)
+	if err := confirmForm.Run(); err != nil {
+		return nil, err
+	}
+	if !drill {
+		return nil, nil
+	}
+
+	selected := make(map[string][]string)
+	for _, mu := range candidates {
+		defaults := make(map[string]struct{})
+		for _, pkg := range current.SelectedPackages[mu.Module.Path] {
+			defaults[pkg] = struct{}{}
+		}
+
+		options := make([]huh.Option[string], 0, len(mu.Packages))
+		value := make([]string, 0, len(mu.Packages))
+		for _, pkg := range mu.Packages {
+			options = append(options, huh.NewOption(pkg.ImportPath, pkg.ImportPath))
+			if _, ok := defaults[pkg.ImportPath]; ok || len(defaults) == 0 {
+				value = append(value, pkg.ImportPath)
 			}
 		}
+
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewMultiSelect[string]().
+					Title(fmt.Sprintf("Packages from %s", mu.Module.Path)).
+					Options(options...).
+					Value(&value),
+			),
+		)
+		if err := form.Run(); err != nil {
+			return nil, err
+		}
+		if len(value) > 0 && len(value) < len(mu.Packages) {
+			selected[mu.Module.Path] = value
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil
 	}
+	return selected, nil
+}
 
-	return selection, nil
+// splitModules parses a comma-separated module list, dropping empty entries.
+func splitModules(s string) []string {
+	var names []string
+	for _, part := range strings.Split(s, ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateModules returns an error listing the names that are not in the
+// module graph, with the closest known path suggested for each.
+func validateModules(names []string, known []discover.Module) error {
+	paths := make(map[string]struct{}, len(known))
+	for _, mod := range known {
+		paths[mod.Path] = struct{}{}
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if _, ok := paths[name]; ok {
+			continue
+		}
+		if suggestion := closestModule(name, known); suggestion != "" {
+			unknown = append(unknown, fmt.Sprintf("%s (did you mean %s?)", name, suggestion))
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("not in the module graph: %s", strings.Join(unknown, ", "))
+}
+
+// closestModule returns the known module path nearest to name by edit
+// distance, or "" when nothing is close enough to be a plausible typo.
+func closestModule(name string, known []discover.Module) string {
+	best, bestDist := "", -1
+	for _, mod := range known {
+		d := levenshtein(name, mod.Path)
+		if bestDist < 0 || d < bestDist || (d == bestDist && mod.Path < best) {
+			best, bestDist = mod.Path, d
+		}
+	}
+	if bestDist < 0 || bestDist > max(2, len(name)/4) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Confirm asks a yes/no question and returns the answer.
+func Confirm(title string) (bool, error) {
+	var ok bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Value(&ok),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return false, err
+	}
+	return ok, nil
 }
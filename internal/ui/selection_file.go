@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/natedelduca/go-rag-pack/internal/discover"
+)
+
+// LoadSelection reads a Selection from a JSON file, for choosing without the
+// interactive form. Unknown keys are rejected, and selected and manual
+// modules must be in proj's module graph. SelectedPackages may only narrow
+// modules that are selected, to packages those modules provide.
+func LoadSelection(path string, proj discover.Project) (Selection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Selection{}, err
+	}
+	var sel Selection
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sel); err != nil {
+		return Selection{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateSelection(sel, proj); err != nil {
+		return Selection{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return sel, nil
+}
+
+// validateSelection checks sel's modules and packages against proj.
+func validateSelection(sel Selection, proj discover.Project) error {
+	if !sel.IncludeModules && (len(sel.SelectedModules) > 0 || len(sel.ManualModules) > 0 || len(sel.SelectedPackages) > 0) {
+		return fmt.Errorf("modules are listed but includeModules is false")
+	}
+	if err := validateModules(slices.Concat(sel.SelectedModules, sel.ManualModules), proj.AllModules); err != nil {
+		return err
+	}
+
+	packages := make(map[string][]string, len(proj.ThirdParty))
+	for _, mu := range proj.ThirdParty {
+		for _, pkg := range mu.Packages {
+			packages[mu.Module.Path] = append(packages[mu.Module.Path], pkg.ImportPath)
+		}
+	}
+	for mod, paths := range sel.SelectedPackages {
+		if !slices.Contains(sel.SelectedModules, mod) {
+			return fmt.Errorf("selectedPackages names %s, which is not in selectedModules", mod)
+		}
+		known, ok := packages[mod]
+		if !ok {
+			// Only modules the project imports have discovered packages.
+			continue
+		}
+		for _, path := range paths {
+			if !slices.Contains(known, path) {
+				return fmt.Errorf("selectedPackages: %s is not a package of %s used by the project", path, mod)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package ragpack
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsDirOutput reports whether an output path names a directory, written as
+// one file per package, by ending in a path separator.
+func IsDirOutput(outPath string) bool {
+	return strings.HasSuffix(outPath, "/") || strings.HasSuffix(outPath, string(os.PathSeparator))
+}
+
+// WritePerPackage groups chunks by import path and writes each group with
+// write to dir/<import path><ext>, so github.com/org/repo/pkg lands in
+// dir/github.com/org/repo/pkg.jsonl. It returns how many files it wrote.
+func WritePerPackage(dir, ext string, chunks []Chunk, write Writer) (int, error) {
+	groups := make(map[string][]Chunk)
+	var order []string
+	for _, ch := range chunks {
+		key := ch.Metadata.ImportPath
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], ch)
+	}
+	for _, importPath := range order {
+		path := filepath.Join(dir, packageFileName(importPath)+ext)
+		if err := write(path, groups[importPath]); err != nil {
+			return 0, err
+		}
+	}
+	return len(order), nil
+}
+
+// PackageFiles lists, sorted, the files under dir that WritePerPackage could
+// have written with one of exts: regular files with that extension whose
+// path is already in packageFileName form. Anything else in dir is left out,
+// so callers can remove a per-package output without touching other files.
+// A missing dir lists nothing.
+func PackageFiles(dir string, exts ...string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		for _, ext := range exts {
+			stem, ok := strings.CutSuffix(rel, ext)
+			if ok && stem != "" && packageFileName(filepath.ToSlash(stem)) == stem {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// packageFileName turns an import path into a relative file path without an
+// extension. Characters outside the safe set become "_", as do "." and ".."
+// elements, so no import path can escape the output directory.
+func packageFileName(importPath string) string {
+	if importPath == "" {
+		importPath = "_"
+	}
+	parts := strings.Split(importPath, "/")
+	for i, part := range parts {
+		part = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._-~+", r):
+				return r
+			}
+			return '_'
+		}, part)
+		if part == "" || part == "." || part == ".." {
+			part = "_"
+		}
+		parts[i] = part
+	}
+	return filepath.Join(parts...)
+}
@@ -0,0 +1,282 @@
+// Package ragpack is the library form of go-rag-pack: it discovers a Go
+// project's packages, chunks their declarations and writes the chunks for
+// retrieval-augmented generation, as the go-rag-pack build command does.
+//
+// Run performs a whole build. The steps it is made of are exported too, so
+// custom pipelines can discover, select, chunk or write on their own:
+//
+//	cfg, err := ragpack.LoadConfig(root, "", true)
+//	project, err := ragpack.Discover(root, false, cfg.GoListRetries)
+//	sources, err := ragpack.Sources(root, cfg, project, ragpack.SourceOptions{})
+//	opts, err := ragpack.ChunkOptions(cfg)
+//	chunks, err := ragpack.Build(sources, opts)
+//	err = ragpack.WriteJSONL("docs.jsonl", chunks)
+//
+// The types are aliases of the tool's own, so values pass between the
+// steps unchanged.
+package ragpack
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+	"github.com/natedelduca/go-rag-pack/internal/config"
+	"github.com/natedelduca/go-rag-pack/internal/discover"
+	"github.com/natedelduca/go-rag-pack/internal/output"
+)
+
+type (
+	// Config is the project configuration read from .go-rag-pack.json
+	// or .go-rag-pack.yaml.
+	Config = config.Config
+	// Chunk is one retrievable piece of documentation.
+	Chunk = chunk.Chunk
+	// Metadata describes where a chunk comes from.
+	Metadata = chunk.Metadata
+	// Options controls how packages are chunked.
+	Options = chunk.Options
+	// PackageSource is one package directory to chunk.
+	PackageSource = chunk.PackageSource
+	// SourceKind says whether a package is the project's, the stdlib's or
+	// a third-party module's.
+	SourceKind = chunk.SourceKind
+	// Project is what discovery found about a project and its dependencies.
+	Project = discover.Project
+	// Package is a package reported by go list.
+	Package = discover.Package
+	// Module is a module of the project's build list.
+	Module = discover.Module
+)
+
+// Source kinds of a PackageSource.
+const (
+	SourceProject    = chunk.SourceProject
+	SourceStdlib     = chunk.SourceStdlib
+	SourceThirdParty = chunk.SourceThirdParty
+)
+
+// Output formats accepted by WriterFor and the config's format field.
+const (
+	FormatJSONL      = "jsonl"
+	FormatLlamaIndex = "llamaindex"
+	FormatCSV        = "csv"
+)
+
+// A Writer writes chunks to a file, gzip-compressed when path ends in .gz.
+type Writer func(path string, chunks []Chunk) error
+
+// Writers for each output format.
+var (
+	WriteJSONL      Writer = output.WriteJSONL
+	WriteLlamaIndex Writer = output.WriteLlamaIndex
+	WriteCSV        Writer = output.WriteCSV
+)
+
+// WriterFor returns the writer for format, "" meaning jsonl, and the file
+// extension its output uses.
+func WriterFor(format string) (Writer, string, error) {
+	switch format {
+	case "", FormatJSONL:
+		return WriteJSONL, ".jsonl", nil
+	case FormatLlamaIndex:
+		return WriteLlamaIndex, ".jsonl", nil
+	case FormatCSV:
+		return WriteCSV, ".csv", nil
+	}
+	return nil, "", fmt.Errorf("unknown format %q (want %s, %s or %s)", format, FormatJSONL, FormatLlamaIndex, FormatCSV)
+}
+
+// DefaultConfig returns the configuration used for a project rooted at root
+// that has no config file.
+func DefaultConfig(root string) Config {
+	return config.Default(root)
+}
+
+// LoadConfig returns the effective configuration of the project at root:
+// defaults, the global config, the config file at configPath (or the one
+// found in root when it is empty), its local override and the GO_RAG_PACK_*
+// environment variables. When strict, unknown keys are errors.
+func LoadConfig(root, configPath string, strict bool) (Config, error) {
+	if configPath == "" {
+		configPath = config.Find(root)
+	} else if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(root, configPath)
+	}
+	cfg, err := config.LoadLayered(root, configPath, strict)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg = config.ApplyEnv(cfg)
+	cfg.LastProjectRoot = root
+	if cfg.OutputPath == "" {
+		cfg.OutputPath = filepath.Join("rag", "go_docs.jsonl")
+	}
+	return cfg, nil
+}
+
+// Discover lists the project at root, its modules and the packages it
+// imports. go list output is cached in the user cache directory unless
+// refresh is set or no cache directory exists. Transient network errors are
+// retried up to retries times.
+func Discover(root string, refresh bool, retries int) (Project, error) {
+	cacheDir, err := discover.DefaultCacheDir()
+	if err != nil {
+		return discover.Discover(root, retries)
+	}
+	return discover.DiscoverCached(root, cacheDir, refresh, retries)
+}
+
+// Implementations type-checks the project at root and returns the
+// interfaces each of its types implements, for Options.Implements. The
+// cross-references are optional, so failures only warn: packages that do
+// not type-check are left out, and when loading fails altogether it returns
+// nil.
+func Implementations(root string, logger *slog.Logger) map[string][]string {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	impls, pkgErrs, err := discover.Implementations(root)
+	if err != nil {
+		logger.Warn("type-checking failed; chunks get no implements", "err", err)
+		return nil
+	}
+	for _, err := range pkgErrs {
+		logger.Warn("package does not type-check; its types get no implements", "err", err)
+	}
+	return impls
+}
+
+// Build chunks every source with opts, one after another, and returns the
+// chunks sorted as opts.Sort asks.
+func Build(sources []PackageSource, opts Options) ([]Chunk, error) {
+	return chunk.Build(sources, opts)
+}
+
+// ChunkOptions translates cfg into chunking options, validating its
+// patterns, kinds and enum values. Options that need files or the network,
+// such as the topic map or the Git commit for source URLs, are left for the
+// caller to fill.
+func ChunkOptions(cfg Config) (Options, error) {
+	var symbolFilter *regexp.Regexp
+	if cfg.SymbolFilter != "" {
+		pattern := cfg.SymbolFilter
+		if cfg.SymbolFilterIgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return chunk.Options{}, fmt.Errorf("symbol filter: %w", err)
+		}
+		symbolFilter = re
+	}
+
+	switch cfg.Sort {
+	case "", chunk.SortPath, chunk.SortSource:
+	default:
+		return chunk.Options{}, fmt.Errorf("unknown sort %q (want %s or %s)", cfg.Sort, chunk.SortPath, chunk.SortSource)
+	}
+
+	for _, pattern := range cfg.SkipFilePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return chunk.Options{}, fmt.Errorf("skipFilePatterns: %q: %w", pattern, err)
+		}
+	}
+
+	switch cfg.TestPackages {
+	case "", chunk.TestPackageInternal, chunk.TestPackageExternal:
+	default:
+		return chunk.Options{}, fmt.Errorf("unknown test packages %q (want %s or %s)", cfg.TestPackages, chunk.TestPackageInternal, chunk.TestPackageExternal)
+	}
+
+	var transformers []chunk.Transformer
+	for _, rule := range cfg.Redact {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return chunk.Options{}, fmt.Errorf("redact pattern %q: %w", rule.Pattern, err)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED]"
+		}
+		transformers = append(transformers, chunk.Redactor{Pattern: re, Replacement: replacement})
+	}
+	if len(cfg.RewriteImportPaths) > 0 {
+		transformers = append(transformers, chunk.ImportPathRewriter(cfg.RewriteImportPaths))
+	}
+
+	var kinds map[string]bool
+	for _, kind := range cfg.Kinds {
+		if !slices.Contains(chunk.AllKinds, kind) {
+			return chunk.Options{}, fmt.Errorf("unknown kind %q (want one of %s)", kind, strings.Join(chunk.AllKinds, ", "))
+		}
+		if kinds == nil {
+			kinds = make(map[string]bool)
+		}
+		kinds[kind] = true
+	}
+
+	var overrides map[string]chunk.ModuleOverride
+	for mod, ov := range cfg.ModuleOverrides {
+		if overrides == nil {
+			overrides = make(map[string]chunk.ModuleOverride, len(cfg.ModuleOverrides))
+		}
+		overrides[mod] = chunk.ModuleOverride{ExportedOnly: ov.ExportedOnly, IncludeTests: ov.IncludeTests, RequireDoc: ov.RequireDoc}
+	}
+
+	var sourceURLs map[chunk.SourceKind]string
+	if cfg.RepoURLTemplate != "" || len(cfg.SourceURLTemplates) > 0 {
+		sourceURLs = make(map[chunk.SourceKind]string)
+		for kind, tmpl := range cfg.SourceURLTemplates {
+			sourceURLs[chunk.SourceKind(kind)] = tmpl
+		}
+		if cfg.RepoURLTemplate != "" {
+			sourceURLs[chunk.SourceProject] = cfg.RepoURLTemplate
+		}
+	}
+
+	return chunk.Options{
+		DocPolicy:              cfg.DocPolicy,
+		InlineDoc:              cfg.InlineDoc,
+		IncludeImports:         cfg.IncludeImports,
+		FieldChunks:            cfg.FieldChunks,
+		SignatureOnly:          cfg.SignatureOnly,
+		SkipDeprecatedPackages: cfg.SkipDeprecatedPackages,
+		StableIDs:              cfg.StableIDs,
+		MaxTokens:              cfg.MaxTokens,
+		ChunkOverlap:           cfg.ChunkOverlap,
+		ExportedOnly:           cfg.ExportedOnly,
+		RequireDoc:             cfg.RequireDoc,
+		ModuleOverrides:        overrides,
+		Directives:             cfg.Directives,
+		NativeCodeNotes:        cfg.NativeCodeNotes,
+		CommandSummaries:       cfg.CommandSummaries,
+		TypeBundles:            cfg.TypeBundles,
+		TypeBundleMaxBytes:     cfg.TypeBundleMaxBytes,
+		Kinds:                  kinds,
+		UsageExamples:          cfg.UsageExamples,
+		Transformers:           transformers,
+		MaxFileBytes:           cfg.MaxFileBytes,
+		AlwaysEmitPackageDoc:   cfg.AlwaysEmitPackageDoc,
+		SkipFilePatterns:       cfg.SkipFilePatterns,
+		ReplaceSkipPatterns:    cfg.ReplaceSkipPatterns,
+		SkipGenerated:          cfg.SkipGenerated,
+		IncludeTests:           cfg.IncludeTests,
+		TestPackages:           cfg.TestPackages,
+		SymbolFilter:           symbolFilter,
+		OnlySymbols:            chunk.NewAllowlist(cfg.OnlySymbols),
+		Sort:                   cfg.Sort,
+		PathStyle:              cfg.PathStyle,
+		Root:                   cfg.LastProjectRoot,
+		SourceURLs:             sourceURLs,
+		MethodTypeContext:      cfg.MethodTypeContext,
+		AttachExamples:         cfg.AttachExamples,
+		SearchKeywords:         cfg.SearchKeywords,
+		MaxTOCBytes:            cfg.MaxTOCBytes,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package ragpack
+
+import "testing"
+
+func TestChunkOptionsSymbolFilterIgnoreCase(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		symbol     string
+		want       bool
+	}{
+		{"exact case", false, "Handler", true},
+		{"other case", false, "handler", false},
+		{"other case ignored", true, "HANDLER", true},
+		{"no match", true, "Server", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			cfg.SymbolFilter = "Handler"
+			cfg.SymbolFilterIgnoreCase = tt.ignoreCase
+			opts, err := ChunkOptions(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := opts.SymbolFilter.MatchString(tt.symbol); got != tt.want {
+				t.Errorf("filter matches %q = %v, want %v", tt.symbol, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkOptionsBadSymbolFilter(t *testing.T) {
+	cfg := DefaultConfig(t.TempDir())
+	cfg.SymbolFilter = "("
+	if _, err := ChunkOptions(cfg); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,214 @@
+package ragpack
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+)
+
+// RunOptions configures Run. The zero value builds the project in the
+// working directory from its config file, like go-rag-pack build.
+type RunOptions struct {
+	// Root is the project directory; empty means the working directory.
+	Root string
+	// ConfigPath is the config file, relative to Root; empty finds the
+	// project config in Root.
+	ConfigPath string
+	// Config, when set, is used instead of loading the config file.
+	Config *Config
+	// Auto selects the project, its stdlib imports and every third-party
+	// module, ignoring the config's selection.
+	Auto bool
+	// Packages chunks only these import paths, ignoring the config's
+	// project, stdlib and module selection.
+	Packages []string
+	// ExcludeModules drops these modules from the selected sources, after
+	// Auto and the config's selection; see ExcludeModules.
+	ExcludeModules []string
+	// Refresh ignores cached go list output.
+	Refresh bool
+	// Output overrides the config's output path, relative to Root. A path
+	// ending in .gz is gzip-compressed, and one ending in a separator is a
+	// directory holding one file per package, as with the CLI.
+	Output string
+	// NoWrite skips writing the output; the chunks are only returned.
+	NoWrite bool
+	// Logger receives warnings and progress notes; nil discards them.
+	Logger *slog.Logger
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	// Chunks holds every chunk built, in output order.
+	Chunks []Chunk
+	// OutputPath is the absolute file or directory written, or "" with
+	// NoWrite.
+	OutputPath string
+	// Project is what discovery found.
+	Project Project
+}
+
+// Run performs a build as go-rag-pack build does: it loads the config,
+// discovers the project, selects and chunks the packages, adds module-info
+// chunks, applies the config's idTemplate and writes the chunks in the
+// config's format. Build extras with their own flags, such as embeddings,
+// incremental --since builds or the SQLite and symbol index outputs, are
+// left to callers, which can apply them to Result.Chunks.
+func Run(opts RunOptions) (Result, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	root := opts.Root
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return Result{}, err
+		}
+		root = wd
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var cfg Config
+	if opts.Config != nil {
+		cfg = *opts.Config
+		if cfg.LastProjectRoot == "" {
+			cfg.LastProjectRoot = root
+		}
+	} else if cfg, err = LoadConfig(root, opts.ConfigPath, true); err != nil {
+		return Result{}, err
+	}
+	if len(opts.Packages) > 0 {
+		if opts.Auto {
+			return Result{}, errors.New("RunOptions.Packages and Auto cannot be combined")
+		}
+		cfg.IncludeProject = false
+		cfg.IncludeStdlib = false
+		cfg.StdlibGroups = nil
+		cfg.SelectedModules = nil
+		cfg.ManualModules = nil
+	}
+
+	var idTemplate *chunk.IDTemplate
+	if cfg.IDTemplate != "" {
+		if idTemplate, err = chunk.ParseIDTemplate(cfg.IDTemplate); err != nil {
+			return Result{}, err
+		}
+	}
+	write, ext, err := WriterFor(cfg.Format)
+	if err != nil {
+		return Result{}, err
+	}
+	chunkOpts, err := ChunkOptions(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	if cfg.TopicMap != "" {
+		topicMap := cfg.TopicMap
+		if !filepath.IsAbs(topicMap) {
+			topicMap = filepath.Join(root, topicMap)
+		}
+		if chunkOpts.Topics, err = chunk.LoadTopicRules(topicMap); err != nil {
+			return Result{}, err
+		}
+	}
+	if cfg.WithTypes {
+		chunkOpts.Implements = Implementations(root, logger)
+	}
+	chunkOpts.OnLargeFile = func(path string, size int64) {
+		logger.Warn("skipping file larger than maxFileBytes", "path", path, "bytes", size, "maxFileBytes", cfg.MaxFileBytes)
+	}
+	if cfg.SkipErrors {
+		chunkOpts.OnFileError = func(path string, err error) {
+			logger.Warn("skipping file", "path", path, "err", err)
+		}
+	}
+
+	project, err := Discover(root, opts.Refresh, cfg.GoListRetries)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, pkg := range project.Skipped {
+		logger.Warn("skipping package that go list could not load", "importPath", pkg.ImportPath, "err", pkg.Error.Err)
+	}
+	if err := CheckModuleOverrides(cfg, project); err != nil {
+		return Result{}, err
+	}
+	if opts.Auto {
+		cfg = SelectAll(cfg, project)
+	}
+	sources, err := Sources(root, cfg, project, SourceOptions{Packages: opts.Packages, Logger: logger})
+	if err != nil {
+		return Result{}, err
+	}
+	cfg, sources, unmatched := ExcludeModules(cfg, sources, opts.ExcludeModules)
+	for _, pattern := range unmatched {
+		logger.Warn("excluded module is not among the selected sources", "module", pattern)
+	}
+	if len(sources) == 0 {
+		return Result{}, errors.New("no sources selected")
+	}
+	if cfg.MaxPackages > 0 && len(sources) > cfg.MaxPackages {
+		return Result{}, fmt.Errorf("%d packages selected, more than maxPackages (%d)", len(sources), cfg.MaxPackages)
+	}
+
+	chunks, err := Build(sources, chunkOpts)
+	if err != nil {
+		return Result{}, err
+	}
+	if cfg.ModuleInfo {
+		infos, err := ModuleInfo(project, cfg, chunkOpts)
+		if err != nil {
+			return Result{}, err
+		}
+		if len(infos) > 0 {
+			chunks = append(chunks, infos...)
+			chunk.Sort(chunks, chunkOpts)
+		}
+	}
+	if idTemplate != nil {
+		if err := idTemplate.Apply(chunks); err != nil {
+			return Result{}, err
+		}
+	}
+	for _, name := range chunkOpts.OnlySymbols.Unmatched() {
+		logger.Warn("onlySymbols name matched no declaration", "name", name)
+	}
+
+	res := Result{Chunks: chunks, Project: project}
+	if opts.NoWrite {
+		return res, nil
+	}
+	outPath := cfg.OutputPath
+	if opts.Output != "" {
+		outPath = opts.Output
+	}
+	if outPath == "" {
+		outPath = filepath.Join("rag", "go_docs.jsonl")
+	}
+	dirOutput := IsDirOutput(outPath)
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(root, outPath)
+	}
+	res.OutputPath = outPath
+	if dirOutput {
+		files, err := WritePerPackage(outPath, ext, chunks, write)
+		if err != nil {
+			return Result{}, err
+		}
+		logger.Info(fmt.Sprintf("wrote %d chunks to %d package file(s) under %s", len(chunks), files, outPath))
+		return res, nil
+	}
+	if err := write(outPath, chunks); err != nil {
+		return Result{}, err
+	}
+	logger.Info(fmt.Sprintf("wrote %d chunks to %s", len(chunks), outPath))
+	return res, nil
+}
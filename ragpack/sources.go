@@ -0,0 +1,508 @@
+package ragpack
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+	"github.com/natedelduca/go-rag-pack/internal/discover"
+)
+
+// SourceOptions adjusts how Sources selects packages beyond the config.
+type SourceOptions struct {
+	// Packages adds these import paths, resolved with go list from root.
+	Packages []string
+	// SkipProject leaves out the project's own packages, for callers that
+	// chunk them separately.
+	SkipProject bool
+	// Download runs go mod download for selected modules missing from the
+	// module cache.
+	Download bool
+	// Logger receives progress notes and warnings about skipped modules;
+	// nil discards them.
+	Logger *slog.Logger
+}
+
+// SelectAll returns cfg selecting everything discovery found: the project,
+// the stdlib packages it imports and every third-party module, as build
+// --auto does.
+func SelectAll(cfg Config, project Project) Config {
+	cfg.IncludeProject = true
+	cfg.IncludeStdlib = len(project.StdlibPackages) > 0
+	cfg.SelectedModules = nil
+	for _, mod := range project.ThirdParty {
+		cfg.SelectedModules = append(cfg.SelectedModules, mod.Module.Path)
+	}
+	cfg.ManualModules = nil
+	cfg.SelectedPackages = nil
+	return cfg
+}
+
+// Sources returns the packages cfg selects from project: the project's own,
+// the imported stdlib packages and curated stdlib groups, the packages of
+// selected modules (scanning the module directory for manually added ones)
+// and any opts.Packages. The result holds one source per import path,
+// sorted by import path.
+func Sources(root string, cfg Config, project Project, opts SourceOptions) ([]PackageSource, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	var sources []chunk.PackageSource
+	if cfg.IncludeProject && !opts.SkipProject {
+		for _, pkg := range project.InternalPackages {
+			sources = append(sources, chunk.PackageSource{
+				ModulePath:    project.MainModule.Path,
+				ModuleVersion: project.MainModule.Version,
+				ModuleDir:     project.Root,
+				ImportPath:    pkg.ImportPath,
+				Dir:           pkg.Dir,
+				Kind:          chunk.SourceProject,
+			})
+		}
+	}
+
+	stdRoot := filepath.Join(runtime.GOROOT(), "src")
+	if cfg.IncludeStdlib {
+		for _, pkg := range project.StdlibPackages {
+			if pkg.Dir == "" || discover.MatchStdlibExclude(pkg.ImportPath, cfg.ExcludeStdlib) || discover.ThinPackage(pkg.Dir, cfg.MinStdlibExports) {
+				continue
+			}
+			sources = append(sources, chunk.PackageSource{
+				ModulePath:    "std",
+				ModuleVersion: "",
+				ModuleDir:     stdRoot,
+				ImportPath:    pkg.ImportPath,
+				Dir:           pkg.Dir,
+				Kind:          chunk.SourceStdlib,
+			})
+		}
+	}
+
+	if len(cfg.StdlibGroups) > 0 {
+		prefixes, err := discover.ExpandStdlibGroups(cfg.StdlibGroups)
+		if err != nil {
+			return nil, err
+		}
+		pkgs, err := discover.StdlibByPrefix(root, prefixes)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			if discover.MatchStdlibExclude(pkg.ImportPath, cfg.ExcludeStdlib) || discover.ThinPackage(pkg.Dir, cfg.MinStdlibExports) {
+				continue
+			}
+			sources = append(sources, chunk.PackageSource{
+				ModulePath: "std",
+				ModuleDir:  stdRoot,
+				ImportPath: pkg.ImportPath,
+				Dir:        pkg.Dir,
+				Kind:       chunk.SourceStdlib,
+			})
+		}
+	}
+
+	if selectedModules := selectedModules(cfg); len(selectedModules) > 0 {
+		modUsage := make(map[string]discover.ModuleUsage)
+		for _, mu := range project.ThirdParty {
+			modUsage[mu.Module.Path] = mu
+		}
+		allModules := make(map[string]discover.Module)
+		for _, mod := range project.AllModules {
+			allModules[mod.Path] = mod
+		}
+
+		for path := range selectedModules {
+			var onlyPackages map[string]struct{}
+			if pkgs := cfg.SelectedPackages[path]; len(pkgs) > 0 {
+				onlyPackages = make(map[string]struct{}, len(pkgs))
+				for _, pkg := range pkgs {
+					onlyPackages[pkg] = struct{}{}
+				}
+			}
+
+			if mu, ok := modUsage[path]; ok {
+				for _, pkg := range mu.Packages {
+					if _, keep := onlyPackages[pkg.ImportPath]; onlyPackages != nil && !keep {
+						continue
+					}
+					dir := pkg.Dir
+					if dir == "" && pkg.Module != nil {
+						dir = pkg.Module.Dir
+					}
+					if dir == "" {
+						continue
+					}
+					moduleDir := mu.Module.Dir
+					if moduleDir == "" && pkg.Module != nil {
+						moduleDir = pkg.Module.Dir
+					}
+					if moduleDir == "" {
+						moduleDir = dir
+					}
+					sources = append(sources, chunk.PackageSource{
+						ModulePath:    mu.Module.Path,
+						ModuleVersion: mu.Module.Version,
+						ModuleDir:     moduleDir,
+						ImportPath:    pkg.ImportPath,
+						Dir:           dir,
+						Kind:          chunk.SourceThirdParty,
+					})
+				}
+				continue
+			}
+
+			// Manual module handling: discover packages by scanning the module directory.
+			module, ok := allModules[path]
+			if !ok {
+				logger.Warn("module not found; skipping", "module", path)
+				continue
+			}
+			if module.Dir == "" && opts.Download {
+				logger.Info("downloading module", "module", module.Path, "version", module.Version)
+				downloaded, err := discover.DownloadModule(root, module.Path, module.Version)
+				if err != nil {
+					logger.Warn("module download failed; skipping", "module", path, "err", err)
+					continue
+				}
+				module.Dir, module.GoMod = downloaded.Dir, downloaded.GoMod
+			}
+			if module.Dir == "" {
+				logger.Warn("module has no source directory; skipping (--download fetches it)", "module", path)
+				continue
+			}
+			pkgs, err := scanModulePackages(module, cfg.ManualScanDepth)
+			if err != nil {
+				logger.Warn("module scan failed; skipping", "module", path, "err", err)
+				continue
+			}
+			for _, pkg := range pkgs {
+				if _, keep := onlyPackages[pkg.ImportPath]; onlyPackages != nil && !keep {
+					continue
+				}
+				sources = append(sources, chunk.PackageSource{
+					ModulePath:    module.Path,
+					ModuleVersion: module.Version,
+					ModuleDir:     module.Dir,
+					ImportPath:    pkg.ImportPath,
+					Dir:           pkg.Dir,
+					Kind:          chunk.SourceThirdParty,
+				})
+			}
+		}
+	}
+
+	if len(opts.Packages) > 0 {
+		pkgs, err := discover.ResolvePackages(root, opts.Packages)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			sources = append(sources, SourceOf(pkg, root))
+		}
+	}
+	return dedupeSources(sources, logger), nil
+}
+
+// CheckModuleOverrides reports an error for the first cfg.ModuleOverrides
+// key, in sorted order, that names neither the main module, a module of the
+// build list nor "std", so a typo does not silently leave the global
+// settings in place.
+func CheckModuleOverrides(cfg Config, project Project) error {
+	known := map[string]bool{"std": true, project.MainModule.Path: true}
+	for _, mod := range project.AllModules {
+		known[mod.Path] = true
+	}
+	keys := make([]string, 0, len(cfg.ModuleOverrides))
+	for mod := range cfg.ModuleOverrides {
+		keys = append(keys, mod)
+	}
+	slices.Sort(keys)
+	for _, mod := range keys {
+		if !known[mod] {
+			return fmt.Errorf("moduleOverrides: unknown module %q (want the main module, a module of the build list or std)", mod)
+		}
+	}
+	return nil
+}
+
+// selectedModules returns the set of third-party and manually added modules
+// cfg selects.
+func selectedModules(cfg Config) map[string]struct{} {
+	selected := make(map[string]struct{})
+	for _, mod := range cfg.SelectedModules {
+		selected[mod] = struct{}{}
+	}
+	for _, mod := range cfg.ManualModules {
+		selected[mod] = struct{}{}
+	}
+	return selected
+}
+
+// ExcludeModules drops the sources of every module matching one of
+// patterns, overriding both the config's selection and SelectAll. The
+// modules also leave cfg's selection, so no module-info chunk or versions
+// entry is written for them. A pattern matches a module path exactly or,
+// when it ends in "/", every module below it, like "github.com/foo/"; "std"
+// matches the standard library. It returns the patterns that matched no
+// source, for callers to warn about.
+func ExcludeModules(cfg Config, sources []PackageSource, patterns []string) (Config, []PackageSource, []string) {
+	if len(patterns) == 0 {
+		return cfg, sources, nil
+	}
+	matched := make(map[string]bool, len(patterns))
+	excluded := func(modulePath string) bool {
+		hit := false
+		for _, pattern := range patterns {
+			if moduleMatches(modulePath, pattern) {
+				matched[pattern] = true
+				hit = true
+			}
+		}
+		return hit
+	}
+	var kept []PackageSource
+	for _, src := range sources {
+		if !excluded(src.ModulePath) {
+			kept = append(kept, src)
+		}
+	}
+	isExcluded := func(modulePath string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool { return moduleMatches(modulePath, pattern) })
+	}
+	cfg.SelectedModules = slices.DeleteFunc(slices.Clone(cfg.SelectedModules), isExcluded)
+	cfg.ManualModules = slices.DeleteFunc(slices.Clone(cfg.ManualModules), isExcluded)
+
+	var unmatched []string
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return cfg, kept, unmatched
+}
+
+// moduleMatches reports whether an --exclude-module pattern matches
+// modulePath: exactly, or as a prefix when the pattern ends in "/".
+func moduleMatches(modulePath, pattern string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(modulePath, pattern)
+	}
+	return modulePath == pattern
+}
+
+// SourceOf returns the source for a package reported by go list from the
+// project rooted at root, classifying it as stdlib, project or third-party.
+func SourceOf(pkg Package, root string) PackageSource {
+	switch {
+	case pkg.Standard:
+		return chunk.PackageSource{
+			ModulePath: "std",
+			ModuleDir:  filepath.Join(runtime.GOROOT(), "src"),
+			ImportPath: pkg.ImportPath,
+			Dir:        pkg.Dir,
+			Kind:       chunk.SourceStdlib,
+		}
+	case pkg.Module != nil && pkg.Module.Main:
+		return chunk.PackageSource{
+			ModulePath:    pkg.Module.Path,
+			ModuleVersion: pkg.Module.Version,
+			ModuleDir:     root,
+			ImportPath:    pkg.ImportPath,
+			Dir:           pkg.Dir,
+			Kind:          chunk.SourceProject,
+		}
+	}
+	src := chunk.PackageSource{
+		ModuleDir:  pkg.Dir,
+		ImportPath: pkg.ImportPath,
+		Dir:        pkg.Dir,
+		Kind:       chunk.SourceThirdParty,
+	}
+	if mod := pkg.Module; mod != nil {
+		src.ModulePath, src.ModuleVersion = mod.Path, mod.Version
+		if mod.Replace != nil && mod.Replace.Dir != "" {
+			src.ModuleDir = mod.Replace.Dir
+		} else if mod.Dir != "" {
+			src.ModuleDir = mod.Dir
+		}
+	}
+	return src
+}
+
+// ModuleInfo builds a module-info chunk for each module cfg selects that
+// appears in the module graph, in module path order.
+func ModuleInfo(project Project, cfg Config, opts Options) ([]Chunk, error) {
+	selected := selectedModules(cfg)
+	var chunks []chunk.Chunk
+	for _, mod := range project.AllModules {
+		if _, ok := selected[mod.Path]; !ok || mod.Main {
+			continue
+		}
+		infos, err := chunk.BuildModuleInfo(chunk.ModuleSource{
+			Path:    mod.Path,
+			Version: mod.Version,
+			GoMod:   mod.GoMod,
+			Kind:    chunk.SourceThirdParty,
+		}, opts)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, infos...)
+	}
+	return chunks, nil
+}
+
+// dedupeSources keeps one source per import path, sorted by import path, so
+// no two packages produce chunks with the same IDs. When an import path comes
+// from several directories, such as a vendored copy and a module cache copy,
+// project sources win over third-party ones and those over stdlib; between
+// sources of the same kind the lexically first directory wins, with a
+// warning.
+func dedupeSources(sources []chunk.PackageSource, logger *slog.Logger) []chunk.PackageSource {
+	if len(sources) <= 1 {
+		return sources
+	}
+	order := func(k chunk.SourceKind) int {
+		switch k {
+		case chunk.SourceProject:
+			return 0
+		case chunk.SourceThirdParty:
+			return 1
+		case chunk.SourceStdlib:
+			return 2
+		default:
+			return 3
+		}
+	}
+	seen := make(map[string]chunk.PackageSource)
+	for _, src := range sources {
+		existing, ok := seen[src.ImportPath]
+		if !ok {
+			seen[src.ImportPath] = src
+			continue
+		}
+		if existing.Dir == src.Dir {
+			if order(src.Kind) < order(existing.Kind) {
+				seen[src.ImportPath] = src
+			}
+			continue
+		}
+		winner, loser := existing, src
+		if o, e := order(src.Kind), order(existing.Kind); o < e || (o == e && src.Dir < existing.Dir) {
+			winner, loser = src, existing
+		}
+		if order(winner.Kind) == order(loser.Kind) {
+			logger.Warn("package found in two directories; using the first", "importPath", src.ImportPath, "dir", winner.Dir, "other", loser.Dir)
+		}
+		seen[src.ImportPath] = winner
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	deduped := make([]chunk.PackageSource, 0, len(paths))
+	for _, path := range paths {
+		deduped = append(deduped, seen[path])
+	}
+	return deduped
+}
+
+// scanModulePackages walks module.Dir for directories holding Go files. A
+// positive maxDepth stops the walk that many directories below the module
+// root, so 1 keeps the root package and its immediate subpackages.
+//
+// Symlinked directories, including module.Dir itself, are followed, as
+// monorepos often link shared code into a module. Packages under a link get
+// import paths from the link's name. Each real directory is visited once, so
+// circular links end the walk and a directory linked twice is chunked under
+// the first path reached.
+func scanModulePackages(module discover.Module, maxDepth int) ([]discover.Package, error) {
+	var packages []discover.Package
+	visited := make(map[string]bool)
+	var walk func(path, rel string) error
+	walk = func(path, rel string) error {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		hasGo := false
+		var subdirs []string
+		for _, entry := range entries {
+			name := entry.Name()
+			isDir := entry.IsDir()
+			if entry.Type()&fs.ModeSymlink != 0 {
+				info, err := os.Stat(filepath.Join(path, name))
+				if err != nil {
+					// A dangling link; nothing to chunk behind it.
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if isDir {
+				switch {
+				case name == "vendor", name == "testdata", strings.HasPrefix(name, "."):
+				default:
+					subdirs = append(subdirs, name)
+				}
+				continue
+			}
+			if strings.HasSuffix(name, ".go") && !chunk.ShouldSkipFile(name) {
+				hasGo = true
+			}
+		}
+
+		if hasGo {
+			importPath := module.Path
+			if rel != "." {
+				importPath = module.Path + "/" + filepath.ToSlash(rel)
+			}
+			packages = append(packages, discover.Package{
+				ImportPath: importPath,
+				Dir:        path,
+				Name:       filepath.Base(path),
+				Module: &discover.Module{
+					Path:    module.Path,
+					Version: module.Version,
+					Dir:     module.Dir,
+				},
+			})
+		}
+
+		for _, name := range subdirs {
+			subRel := name
+			if rel != "." {
+				subRel = filepath.Join(rel, name)
+			}
+			if maxDepth > 0 && strings.Count(filepath.ToSlash(subRel), "/")+1 > maxDepth {
+				continue
+			}
+			if err := walk(filepath.Join(path, name), subRel); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(module.Dir, "."); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
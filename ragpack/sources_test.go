@@ -0,0 +1,198 @@
+package ragpack
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/natedelduca/go-rag-pack/internal/chunk"
+	"github.com/natedelduca/go-rag-pack/internal/discover"
+)
+
+// writeGoFiles creates a Go file in each of dirs, relative to root.
+func writeGoFiles(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		full := filepath.Join(root, dir)
+		if err := os.MkdirAll(full, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		src := "package " + filepath.Base(full) + "\n"
+		if err := os.WriteFile(filepath.Join(full, "x.go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// scannedPaths returns the sorted import paths scanModulePackages finds.
+func scannedPaths(t *testing.T, module discover.Module, maxDepth int) []string {
+	t.Helper()
+	pkgs, err := scanModulePackages(module, maxDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range pkgs {
+		paths = append(paths, p.ImportPath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestScanModulePackagesDepth(t *testing.T) {
+	root := t.TempDir()
+	writeGoFiles(t, root, ".", "a", "a/b", "a/b/c", "a/b/c/d", "vendor/v", "testdata/td", ".hidden")
+	if err := os.MkdirAll(filepath.Join(root, "empty", "deeper"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	module := discover.Module{Path: "example.com/m", Dir: root}
+
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{maxDepth: 0, want: []string{"example.com/m", "example.com/m/a", "example.com/m/a/b", "example.com/m/a/b/c", "example.com/m/a/b/c/d"}},
+		{maxDepth: 1, want: []string{"example.com/m", "example.com/m/a"}},
+		{maxDepth: 3, want: []string{"example.com/m", "example.com/m/a", "example.com/m/a/b", "example.com/m/a/b/c"}},
+		{maxDepth: 10, want: []string{"example.com/m", "example.com/m/a", "example.com/m/a/b", "example.com/m/a/b/c", "example.com/m/a/b/c/d"}},
+	}
+	for _, tt := range tests {
+		if got := scannedPaths(t, module, tt.maxDepth); !slices.Equal(got, tt.want) {
+			t.Errorf("maxDepth %d: packages = %v, want %v", tt.maxDepth, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeSources(t *testing.T) {
+	src := func(importPath, dir string, kind chunk.SourceKind) chunk.PackageSource {
+		return chunk.PackageSource{ImportPath: importPath, Dir: dir, Kind: kind}
+	}
+	tests := []struct {
+		name     string
+		sources  []chunk.PackageSource
+		want     []chunk.PackageSource
+		wantWarn bool
+	}{
+		{
+			name: "vendored copy beats module cache",
+			sources: []chunk.PackageSource{
+				src("example.com/dep", "/cache/example.com/dep@v1.0.0", chunk.SourceThirdParty),
+				src("example.com/dep", "/repo/vendor/example.com/dep", chunk.SourceProject),
+			},
+			want: []chunk.PackageSource{src("example.com/dep", "/repo/vendor/example.com/dep", chunk.SourceProject)},
+		},
+		{
+			name: "same kind keeps first directory",
+			sources: []chunk.PackageSource{
+				src("example.com/dep", "/z/dep", chunk.SourceThirdParty),
+				src("example.com/dep", "/a/dep", chunk.SourceThirdParty),
+			},
+			want:     []chunk.PackageSource{src("example.com/dep", "/a/dep", chunk.SourceThirdParty)},
+			wantWarn: true,
+		},
+		{
+			name: "same directory listed twice",
+			sources: []chunk.PackageSource{
+				src("example.com/dep", "/d", chunk.SourceThirdParty),
+				src("example.com/dep", "/d", chunk.SourceProject),
+			},
+			want: []chunk.PackageSource{src("example.com/dep", "/d", chunk.SourceProject)},
+		},
+		{
+			name: "distinct paths sorted",
+			sources: []chunk.PackageSource{
+				src("fmt", "/goroot/src/fmt", chunk.SourceStdlib),
+				src("example.com/m", "/repo", chunk.SourceProject),
+			},
+			want: []chunk.PackageSource{
+				src("example.com/m", "/repo", chunk.SourceProject),
+				src("fmt", "/goroot/src/fmt", chunk.SourceStdlib),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			got := dedupeSources(tt.sources, logger)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dedupeSources = %+v, want %+v", got, tt.want)
+			}
+			if warned := strings.Contains(logs.String(), "level=WARN"); warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v (log %q)", warned, tt.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+func TestDedupeSourcesNoDuplicateIDs(t *testing.T) {
+	root := t.TempDir()
+	writeGoFiles(t, root, "vendor/example.com/dep", "cache/example.com/dep")
+	sources := []chunk.PackageSource{
+		{ModulePath: "example.com/dep", ModuleDir: filepath.Join(root, "cache/example.com/dep"), ImportPath: "example.com/dep", Dir: filepath.Join(root, "cache/example.com/dep"), Kind: chunk.SourceThirdParty},
+		{ModulePath: "example.com/dep", ModuleDir: filepath.Join(root, "vendor/example.com/dep"), ImportPath: "example.com/dep", Dir: filepath.Join(root, "vendor/example.com/dep"), Kind: chunk.SourceProject},
+	}
+	for _, src := range sources {
+		file := filepath.Join(src.Dir, "x.go")
+		if err := os.WriteFile(file, []byte("// Package dep is for tests.\npackage dep\n\n// Run runs.\nfunc Run() {}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chunks, err := chunk.Build(dedupeSources(sources, slog.New(slog.DiscardHandler)), chunk.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, ch := range chunks {
+		if seen[ch.ID] {
+			t.Errorf("duplicate chunk ID %s", ch.ID)
+		}
+		seen[ch.ID] = true
+		if ch.Metadata.Source != string(chunk.SourceProject) {
+			t.Errorf("%s came from the %s copy, want the vendored project copy", ch.ID, ch.Metadata.Source)
+		}
+	}
+	if len(chunks) == 0 {
+		t.Fatal("no chunks built")
+	}
+}
+
+func TestScanModulePackagesSymlinks(t *testing.T) {
+	shared := t.TempDir()
+	writeGoFiles(t, shared, "lib", "lib/inner")
+	root := t.TempDir()
+	writeGoFiles(t, root, ".", "a")
+	link := func(target, name string) {
+		t.Helper()
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+	link(filepath.Join(shared, "lib"), "linked")           // linked shared code
+	link(root, "a/loop")                                   // cycle back to the root
+	link(filepath.Join(shared, "lib"), "again")            // same directory linked twice
+	link(filepath.Join(root, "missing"), "dangling")       // broken link
+	link(filepath.Join(shared, "lib", "inner"), ".hidden") // dot link skipped
+
+	want := []string{"example.com/m", "example.com/m/a", "example.com/m/again", "example.com/m/again/inner"}
+	got := scannedPaths(t, discover.Module{Path: "example.com/m", Dir: root}, 0)
+	if !slices.Equal(got, want) {
+		t.Errorf("packages = %v, want %v", got, want)
+	}
+
+	// A module root that is itself a link is followed.
+	linkedRoot := filepath.Join(t.TempDir(), "m")
+	if err := os.Symlink(shared, linkedRoot); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"example.com/s/lib", "example.com/s/lib/inner"}
+	if got := scannedPaths(t, discover.Module{Path: "example.com/s", Dir: linkedRoot}, 0); !slices.Equal(got, want) {
+		t.Errorf("linked root: packages = %v, want %v", got, want)
+	}
+}